@@ -10,14 +10,14 @@ import (
 var DefaultLogRequest = func(start time.Time, req *http.Request, resp *http.Response, err error) {
 
 	if err == nil {
-		log.Info("ep=http|method=%s|url=%s|code=%d|req=%d|resp=%d|time=%d",
-			req.Method, req.URL, resp.StatusCode, req.ContentLength, resp.ContentLength,
-			time.Now().Sub(start)/time.Microsecond,
+		log.Get("http").Infow("", log.ContextFields(req.Context()), "ep", "http", "method", req.Method, "url", req.URL,
+			"code", resp.StatusCode, "req", req.ContentLength, "resp", resp.ContentLength,
+			"time", int64(time.Now().Sub(start)/time.Microsecond),
 		)
 	} else {
-		log.Warn("ep=http|method=%s|url=%s|code=%d|req=%d|resp=%d|time=%d|err=%s",
-			req.Method, req.URL, 0, req.ContentLength, 0,
-			time.Now().Sub(start)/time.Microsecond, err,
+		log.Get("http").Warnw("", log.ContextFields(req.Context()), "ep", "http", "method", req.Method, "url", req.URL,
+			"code", 0, "req", req.ContentLength, "resp", 0,
+			"time", int64(time.Now().Sub(start)/time.Microsecond), "err", err,
 		)
 	}
 }
@@ -34,6 +34,12 @@ type Transport struct {
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	start := time.Now()
 
+	// forward request id, RoundTripper should not modify req
+	if rid := log.RequestID(req.Context()); rid != "" && req.Header.Get(log.RequestIDHeader) == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set(log.RequestIDHeader, rid)
+	}
+
 	resp, err := t.RoundTripper.RoundTrip(req)
 	if t.LogRequest != nil {
 		t.LogRequest(start, req, resp, err)
@@ -0,0 +1,40 @@
+package httplog
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JoveYu/zgo/log"
+	"github.com/JoveYu/zgo/log/logtest"
+)
+
+func TestRequestID(t *testing.T) {
+	l := logtest.New(t)
+
+	var rid string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rid = r.Header.Get(log.RequestIDHeader)
+	}))
+	defer server.Close()
+
+	client := http.Client{Transport: DefaultTransport}
+	ctx := log.WithRequestID(context.Background(), "abc")
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req = req.WithContext(ctx)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if rid != "abc" {
+		t.Errorf("request id not forward: %s", rid)
+	}
+	if req.Header.Get(log.RequestIDHeader) != "" {
+		t.Errorf("origin request is modified")
+	}
+	l.AssertLogged(log.LevelInfo, "rid=abc")
+	l.AssertLogged(log.LevelInfo, "ep=http")
+}
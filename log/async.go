@@ -0,0 +1,171 @@
+// async write, log call only put entry into a bounded ring buffer
+// log.Install("app.log", log.WithAsync(4096, log.AsyncDropOldest))
+// call log.Sync or log.Exit before exit, Fatal does it already
+
+package log
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// policy when buffer is full
+const (
+	AsyncBlock = iota
+	AsyncDropOldest
+	AsyncDropNewest
+)
+
+type asyncQueue struct {
+	mu      sync.Mutex
+	notFull *sync.Cond
+	// notify flusher and Sync
+	changed *sync.Cond
+
+	buf     []*Entry
+	head    int
+	count   int
+	policy  int
+	writing bool
+	closed  bool
+	dropped uint64
+
+	write func(*Entry)
+}
+
+func newAsyncQueue(size int, policy int, write func(*Entry)) *asyncQueue {
+	if size <= 0 {
+		size = 1024
+	}
+	q := asyncQueue{
+		buf:    make([]*Entry, size),
+		policy: policy,
+		write:  write,
+	}
+	q.notFull = sync.NewCond(&q.mu)
+	q.changed = sync.NewCond(&q.mu)
+	go q.flush()
+	return &q
+}
+
+func (q *asyncQueue) put(e *Entry) {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		q.write(e)
+		return
+	}
+
+	for q.count == len(q.buf) {
+		switch q.policy {
+		case AsyncDropNewest:
+			q.mu.Unlock()
+			atomic.AddUint64(&q.dropped, 1)
+			return
+		case AsyncDropOldest:
+			q.buf[q.head] = nil
+			q.head = (q.head + 1) % len(q.buf)
+			q.count--
+			atomic.AddUint64(&q.dropped, 1)
+		default:
+			q.notFull.Wait()
+		}
+	}
+
+	q.buf[(q.head+q.count)%len(q.buf)] = e
+	q.count++
+	q.changed.Broadcast()
+	q.mu.Unlock()
+}
+
+func (q *asyncQueue) flush() {
+	batch := make([]*Entry, 0, len(q.buf))
+	for {
+		q.mu.Lock()
+		for q.count == 0 && !q.closed {
+			q.changed.Wait()
+		}
+		if q.count == 0 && q.closed {
+			q.mu.Unlock()
+			return
+		}
+		batch = batch[:0]
+		for ; q.count > 0; q.count-- {
+			batch = append(batch, q.buf[q.head])
+			q.buf[q.head] = nil
+			q.head = (q.head + 1) % len(q.buf)
+		}
+		q.writing = true
+		q.notFull.Broadcast()
+		q.changed.Broadcast()
+		q.mu.Unlock()
+
+		for _, e := range batch {
+			q.write(e)
+		}
+
+		q.mu.Lock()
+		q.writing = false
+		q.changed.Broadcast()
+		q.mu.Unlock()
+	}
+}
+
+// wait until all entries in buffer are written
+func (q *asyncQueue) sync() {
+	q.mu.Lock()
+	for q.count > 0 || q.writing {
+		q.changed.Wait()
+	}
+	q.mu.Unlock()
+}
+
+// write left entries and stop flusher, later put write directly
+func (q *asyncQueue) close() {
+	q.sync()
+	q.mu.Lock()
+	q.closed = true
+	q.changed.Broadcast()
+	q.mu.Unlock()
+}
+
+func WithAsync(size int, policy int) Option {
+	return func(l *LevelLogger) {
+		l.out.async = newAsyncQueue(size, policy, l.out.writeSinks)
+	}
+}
+
+// count of entries dropped by full async buffer
+func (l *LevelLogger) Dropped() uint64 {
+	if l.out.async == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&l.out.async.dropped)
+}
+
+// wait for async buffer and flush sinks
+func (l *LevelLogger) Sync() {
+	// dropped count not reported yet
+	if s := l.sampler; s != nil {
+		s.flush(l)
+	}
+	l.out.sync()
+}
+
+// Sync DefaultLog and all named loggers
+func Sync() {
+	if DefaultLog != nil {
+		DefaultLog.Sync()
+	}
+	for _, l := range Loggers() {
+		l.Sync()
+	}
+}
+
+// Sync then os.Exit, use it instead of os.Exit
+// only DefaultLog and named loggers are synced, Sync others before it
+func Exit(code int) {
+	Sync()
+	os.Exit(code)
+}
@@ -0,0 +1,44 @@
+// color is decided by tty, NO_COLOR and FORCE_COLOR
+// ref: https://no-color.org
+
+package log
+
+import (
+	"io"
+	"os"
+)
+
+const (
+	ColorAuto = iota
+	ColorAlways
+	ColorNever
+)
+
+// explicit option > NO_COLOR > FORCE_COLOR > tty
+func useColor(color int, w io.Writer) bool {
+	switch color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	return isTerminal(w)
+}
+
+func isTerminal(w io.Writer) bool {
+	fp, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,109 @@
+// request id in context.Context, printed by *Ctx functions
+// web.Server set it for every request, sql and httplog print it
+
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// empty if not set
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func NewRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// fields from context, nil if nothing
+func ContextFields(ctx context.Context) Fields {
+	id := RequestID(ctx)
+	if id == "" {
+		return nil
+	}
+	return Fields{{Key: "rid", Value: id}}
+}
+
+func (l *LevelLogger) LogCtx(ctx context.Context, level int, depth int, prefix string, v ...interface{}) {
+	if len(v) == 0 {
+		return
+	}
+	if level >= l.GetLevel() && l.sample(level, depth, formatKey(v)) {
+		fields := ContextFields(ctx)
+		if level >= LevelError {
+			fields = append(fields, stackFields(v)...)
+		}
+		l.output(level, depth+1, prefix, formatMessage(v), fields)
+	}
+}
+
+func (l *LevelLogger) DebugCtx(ctx context.Context, v ...interface{}) {
+	l.LogCtx(ctx, LevelDebug, 3, l.Prefix, v...)
+}
+
+func (l *LevelLogger) InfoCtx(ctx context.Context, v ...interface{}) {
+	l.LogCtx(ctx, LevelInfo, 3, l.Prefix, v...)
+}
+
+func (l *LevelLogger) WarnCtx(ctx context.Context, v ...interface{}) {
+	l.LogCtx(ctx, LevelWarn, 3, l.Prefix, v...)
+}
+
+func (l *LevelLogger) ErrorCtx(ctx context.Context, v ...interface{}) {
+	l.LogCtx(ctx, LevelError, 3, l.Prefix, v...)
+}
+
+func (l *LevelLogger) FatalCtx(ctx context.Context, v ...interface{}) {
+	l.LogCtx(ctx, LevelFatal, 3, l.Prefix, v...)
+	// l may be not DefaultLog or named
+	l.Sync()
+	Exit(1)
+}
+
+func DebugCtx(ctx context.Context, v ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.LogCtx(ctx, LevelDebug, 3, DefaultLog.Prefix, v...)
+	}
+}
+
+func InfoCtx(ctx context.Context, v ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.LogCtx(ctx, LevelInfo, 3, DefaultLog.Prefix, v...)
+	}
+}
+
+func WarnCtx(ctx context.Context, v ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.LogCtx(ctx, LevelWarn, 3, DefaultLog.Prefix, v...)
+	}
+}
+
+func ErrorCtx(ctx context.Context, v ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.LogCtx(ctx, LevelError, 3, DefaultLog.Prefix, v...)
+	}
+}
+
+func FatalCtx(ctx context.Context, v ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.LogCtx(ctx, LevelFatal, 3, DefaultLog.Prefix, v...)
+	}
+	Exit(1)
+}
@@ -0,0 +1,132 @@
+// text output, same as stdlib log with Lshortfile
+// 2019/02/20 16:20:07.123456 sql.go:123: [I] ep=mysql|time=10
+//
+// json output, one object per line
+// {"ts":"...","level":"I","file":"sql.go:123","prefix":"","msg":"...","ep":"mysql"}
+
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	FormatText = iota
+	FormatJSON
+)
+
+const (
+	timeFormatText = "2006/01/02 15:04:05.000000"
+	timeFormatJSON = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// one log line, passed to every Sink
+type Entry struct {
+	Time    time.Time
+	Level   int
+	PC      uintptr
+	File    string
+	Line    int
+	Prefix  string
+	Message string
+	Fields  []Field
+}
+
+func encodeText(e *Entry, color bool) []byte {
+	tag, c := levelTag(e.Level)
+
+	b := bytes.Buffer{}
+	if color {
+		b.WriteString(c)
+	}
+	b.WriteString(e.Time.Format(timeFormatText))
+	b.WriteByte(' ')
+	b.WriteString(shortFile(e))
+	b.WriteString(": ")
+	b.WriteString(tag)
+	b.WriteByte(' ')
+	b.WriteString(e.Prefix)
+	if len(e.Fields) > 0 {
+		b.WriteString(formatFields(e.Message, e.Fields))
+	} else {
+		b.WriteString(e.Message)
+	}
+	if color {
+		b.WriteString(colorReset)
+	}
+	if b.Bytes()[b.Len()-1] != '\n' {
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+var reservedKeys = map[string]bool{
+	"ts": true, "level": true, "file": true, "prefix": true, "msg": true,
+}
+
+func encodeJSON(e *Entry) []byte {
+	tag, _ := levelTag(e.Level)
+
+	b := bytes.Buffer{}
+	b.WriteString(`{"ts":`)
+	writeJSONString(&b, e.Time.Format(timeFormatJSON))
+	b.WriteString(`,"level":`)
+	writeJSONString(&b, tag[1:len(tag)-1])
+	b.WriteString(`,"file":`)
+	writeJSONString(&b, shortFile(e))
+	b.WriteString(`,"prefix":`)
+	writeJSONString(&b, e.Prefix)
+	b.WriteString(`,"msg":`)
+	writeJSONString(&b, e.Message)
+
+	for _, f := range e.Fields {
+		key := f.Key
+		// never overwrite builtin keys
+		if reservedKeys[key] {
+			key = "_" + key
+		}
+		b.WriteByte(',')
+		writeJSONString(&b, key)
+		b.WriteByte(':')
+		b.Write(jsonValue(f.Value))
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func shortFile(e *Entry) string {
+	if e.File == "" {
+		return "???:0"
+	}
+	return filepath.Base(e.File) + ":" + strconv.Itoa(e.Line)
+}
+
+func jsonValue(v interface{}) []byte {
+	switch v := v.(type) {
+	case json.Marshaler:
+		// time.Time and others know how to marshal
+	case error:
+		return jsonString(v.Error())
+	case fmt.Stringer:
+		return jsonString(v.String())
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return jsonString(formatValue(v))
+	}
+	return data
+}
+
+func jsonString(s string) []byte {
+	data, _ := json.Marshal(s)
+	return data
+}
+
+func writeJSONString(b *bytes.Buffer, s string) {
+	b.Write(jsonString(s))
+}
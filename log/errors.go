@@ -0,0 +1,113 @@
+// error with caller stack, stack chain is printed by Error and Fatal
+// err := log.NewError("user %d not found", id)
+// err = log.WrapError(err, "login fail")
+// log.Error("request fail: %v", err)
+
+package log
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+type StackError struct {
+	err   error
+	stack []uintptr
+}
+
+func newStackError(err error) *StackError {
+	pcs := make([]uintptr, 32)
+	// skip Callers, newStackError and NewError/WrapError
+	n := runtime.Callers(3, pcs)
+	return &StackError{
+		err:   err,
+		stack: pcs[:n],
+	}
+}
+
+// same as fmt.Errorf, %w is supported
+func NewError(format string, v ...interface{}) error {
+	return newStackError(fmt.Errorf(format, v...))
+}
+
+// message: err, nil if err is nil
+func WrapError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+	return newStackError(fmt.Errorf("%s: %w", message, err))
+}
+
+func (e *StackError) Error() string {
+	return e.err.Error()
+}
+
+func (e *StackError) Unwrap() error {
+	return e.err
+}
+
+// file:line of each frame, one frame per line
+func (e *StackError) Stack() string {
+	sb := strings.Builder{}
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		sb.WriteString(frame.Function)
+		sb.WriteString("\n\t")
+		sb.WriteString(frame.File)
+		sb.WriteByte(':')
+		sb.WriteString(strconv.Itoa(frame.Line))
+		sb.WriteByte('\n')
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// %+v print message with stack chain
+func (e *StackError) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		fmt.Fprint(s, ErrorStack(e))
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		fmt.Fprint(s, e.Error())
+	}
+}
+
+// stack of every StackError in chain, outermost first, empty if no stack
+func ErrorStack(err error) string {
+	sb := strings.Builder{}
+	for err != nil {
+		var se *StackError
+		if !errors.As(err, &se) {
+			break
+		}
+		if sb.Len() > 0 {
+			sb.WriteString("caused by: ")
+		}
+		sb.WriteString(se.Error())
+		sb.WriteByte('\n')
+		sb.WriteString(se.Stack())
+		err = se.err
+	}
+	return sb.String()
+}
+
+// stack field for errors in v
+func stackFields(v []interface{}) Fields {
+	var fields Fields
+	for _, arg := range v {
+		if err, ok := arg.(error); ok {
+			if stack := ErrorStack(err); stack != "" {
+				fields = append(fields, Field{Key: "stack", Value: stack})
+			}
+		}
+	}
+	return fields
+}
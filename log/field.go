@@ -0,0 +1,140 @@
+// structured key value logging
+// Infow("", "ep", "mysql", "time", 10) => ep=mysql|time=10
+
+package log
+
+import (
+	"fmt"
+	"strings"
+)
+
+const badKey = "!BADKEY"
+
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// ordered key value pairs
+type Fields []Field
+
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, `|`, `\|`, `=`, `\=`, "\n", `\n`)
+
+// convert k1, v1, k2, v2 to Fields, Field and Fields are accepted as it is
+func KV(kv ...interface{}) Fields {
+	fields := make(Fields, 0, len(kv)/2)
+	for i := 0; i < len(kv); i++ {
+		switch v := kv[i].(type) {
+		case Field:
+			fields = append(fields, v)
+		case Fields:
+			fields = append(fields, v...)
+		default:
+			if i+1 >= len(kv) {
+				fields = append(fields, Field{Key: badKey, Value: v})
+				break
+			}
+			key, ok := v.(string)
+			if !ok {
+				key = fmt.Sprint(v)
+			}
+			fields = append(fields, Field{Key: key, Value: kv[i+1]})
+			i++
+		}
+	}
+	return fields
+}
+
+// message|k1=v1|k2=v2, message is omitted if empty
+func formatFields(message string, fields []Field) string {
+	sb := strings.Builder{}
+	sb.WriteString(message)
+	for i, f := range fields {
+		if i > 0 || message != "" {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(fieldEscaper.Replace(f.Key))
+		sb.WriteByte('=')
+		sb.WriteString(fieldEscaper.Replace(formatValue(f.Value)))
+	}
+	return sb.String()
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func (l *LevelLogger) Logw(level int, depth int, prefix string, message string, kv ...interface{}) {
+	if level >= l.GetLevel() && l.sample(level, depth, message) {
+		fields := KV(kv...)
+		if level >= LevelError {
+			fields = append(fields, stackFields(kv)...)
+		}
+		l.output(level, depth+1, prefix, message, fields)
+	}
+}
+
+func (l *LevelLogger) Debugw(message string, kv ...interface{}) {
+	l.Logw(LevelDebug, 3, l.Prefix, message, kv...)
+}
+
+func (l *LevelLogger) Infow(message string, kv ...interface{}) {
+	l.Logw(LevelInfo, 3, l.Prefix, message, kv...)
+}
+
+func (l *LevelLogger) Warnw(message string, kv ...interface{}) {
+	l.Logw(LevelWarn, 3, l.Prefix, message, kv...)
+}
+
+func (l *LevelLogger) Errorw(message string, kv ...interface{}) {
+	l.Logw(LevelError, 3, l.Prefix, message, kv...)
+}
+
+func (l *LevelLogger) Fatalw(message string, kv ...interface{}) {
+	l.Logw(LevelFatal, 3, l.Prefix, message, kv...)
+	// l may be not DefaultLog or named
+	l.Sync()
+	Exit(1)
+}
+
+func Debugw(message string, kv ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.Logw(LevelDebug, 3, DefaultLog.Prefix, message, kv...)
+	}
+}
+
+func Infow(message string, kv ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.Logw(LevelInfo, 3, DefaultLog.Prefix, message, kv...)
+	}
+}
+
+func Warnw(message string, kv ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.Logw(LevelWarn, 3, DefaultLog.Prefix, message, kv...)
+	}
+}
+
+func Errorw(message string, kv ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.Logw(LevelError, 3, DefaultLog.Prefix, message, kv...)
+	}
+}
+
+func Fatalw(message string, kv ...interface{}) {
+	if DefaultLog != nil {
+		DefaultLog.Logw(LevelFatal, 3, DefaultLog.Prefix, message, kv...)
+	}
+	Exit(1)
+}
@@ -0,0 +1,105 @@
+// change level at runtime, by signal or web.LogLevelHandler
+
+package log
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+var levels = []int{LevelDebug, LevelInfo, LevelWarn, LevelError, LevelFatal}
+
+func LevelName(level int) string {
+	switch level {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	case LevelFatal:
+		return "fatal"
+	default:
+		return fmt.Sprintf("level%d", level)
+	}
+}
+
+// accept debug/info/warn/error/fatal or D/I/W/E/F
+func ParseLevel(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for _, level := range levels {
+		name := LevelName(level)
+		if s == name || s == name[:1] {
+			return level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level [%s]", s)
+}
+
+// next level, step > 0 is less verbose, clamped to Debug and Fatal
+func StepLevel(level int, step int) int {
+	idx := 0
+	for i, l := range levels {
+		if level >= l {
+			idx = i
+		}
+	}
+	idx += step
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(levels) {
+		idx = len(levels) - 1
+	}
+	return levels[idx]
+}
+
+// SetLevel and log the change even if new level is higher than Warn
+func (l *LevelLogger) ChangeLevel(level int, by string) {
+	old := int(atomic.SwapInt32(&l.level, int32(level)))
+	name := l.Name
+	if name == "" {
+		name = "default"
+	}
+	l.output(LevelWarn, 3, l.Prefix, "change log level", Fields{
+		{Key: "name", Value: name},
+		{Key: "from", Value: LevelName(old)},
+		{Key: "to", Value: LevelName(level)},
+		{Key: "by", Value: by},
+	})
+}
+
+// level of DefaultLog and named loggers, DefaultLog is "default"
+func Levels() map[string]string {
+	m := map[string]string{}
+	if DefaultLog != nil {
+		m["default"] = LevelName(DefaultLog.GetLevel())
+	}
+	for name, l := range Loggers() {
+		m[name] = LevelName(l.GetLevel())
+	}
+	return m
+}
+
+// DefaultLog by "default", named logger by name, nil if not found
+func Lookup(name string) *LevelLogger {
+	if name == "default" {
+		return DefaultLog
+	}
+	loggersMu.RLock()
+	defer loggersMu.RUnlock()
+	return loggers[name]
+}
+
+// step all loggers, used by signal
+func stepAll(step int, by string) {
+	if DefaultLog != nil {
+		DefaultLog.ChangeLevel(StepLevel(DefaultLog.GetLevel(), step), by)
+	}
+	for _, l := range Loggers() {
+		l.ChangeLevel(StepLevel(l.GetLevel(), step), by)
+	}
+}
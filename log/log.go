@@ -1,17 +1,15 @@
-// TODO log time rotate
-// TODO log size rotate
-// TODO multi logger
 // TODO windows color
-// TODO check tty
 
 package log
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"runtime"
 	"strings"
-	"sync"
+	"sync/atomic"
 )
 
 const (
@@ -41,7 +39,6 @@ const (
 	colorReset = "\033[0m"
 )
 
-// TODO
 const (
 	RotateNo = iota
 	RotateTimeDay
@@ -55,16 +52,22 @@ const (
 
 type LevelLogger struct {
 	*log.Logger
-	fp       *os.File
-	mu       sync.Mutex
+	out      *fanout
+	sink     *WriterSink
+	Name     string
 	Prefix   string
 	Filename string
-	Level    int
-
-	// TODO
-	Rotate    int
-	MaxSize   int
-	MaxBackup int
+	// atomic, changed at runtime by signal or web
+	// exported Level is removed, use GetLevel and SetLevel
+	level   int32
+	Format  int
+	Color   int
+	sampler *sampler
+	// redirect stdlib log at this level, 0 for not redirect
+	stdlog int
+
+	// set Rotate, MaxSize and MaxBackup after Install
+	RotateConf
 }
 
 var (
@@ -79,28 +82,75 @@ func GetLogger() *LevelLogger {
 	return DefaultLog
 }
 
-func Install(dest string) *LevelLogger {
+// option for Install
+type Option func(*LevelLogger)
+
+// register as named logger, see Get
+func WithName(name string) Option {
+	return func(l *LevelLogger) {
+		l.Name = name
+	}
+}
+
+func WithFormat(format int) Option {
+	return func(l *LevelLogger) {
+		l.Format = format
+	}
+}
 
-	var base *log.Logger
-	var fp *os.File
+func WithColor(color int) Option {
+	return func(l *LevelLogger) {
+		l.Color = color
+	}
+}
+
+// dest is "stdout", "stderr", file path or url like "syslog://localhost:514"
+func Install(dest string, opts ...Option) *LevelLogger {
+
+	l := LevelLogger{
+		out:      &fanout{},
+		Prefix:   "",
+		Filename: dest,
+		level:    LevelDebug,
+		Format:   FormatText,
+		Color:    ColorAuto,
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
 
-	if dest == "stdout" {
-		fp = os.Stdout
-		base = log.New(fp, "", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile)
+	if w := stdWriter(dest); w != nil {
+		l.sink = NewWriterSink(w, l.Format, useColor(l.Color, w))
+	} else if strings.Contains(dest, "://") {
+		sink, err := openURLSink(dest)
+		if err != nil {
+			fmt.Printf("can not open log dest: %v\n", err)
+		} else {
+			l.out.add(0, sink)
+		}
 	} else {
-		fp, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+		file, err := newFileSink(dest, l.Format, &l.RotateConf)
 		if err != nil {
 			fmt.Printf("can not open logfile: %v\n", err)
+			l.sink = NewWriterSink(io.Discard, l.Format, false)
+		} else {
+			file.Color = useColor(l.Color, file.w)
+			l.sink = file.WriterSink
 		}
-		base = log.New(fp, "", log.Ldate|log.Ltime|log.Lmicroseconds|log.Lshortfile)
 	}
+	if l.sink != nil {
+		l.out.add(0, l.sink)
+	}
+	// Printf go through sinks, same as Info
+	l.Logger = NewStdLogger(&l, LevelInfo)
 
-	l := LevelLogger{
-		Logger:   base,
-		fp:       fp,
-		Prefix:   "",
-		Filename: dest,
-		Level:    LevelDebug,
+	if l.stdlog > 0 {
+		RedirectStdlog(&l, l.stdlog)
+	}
+
+	if l.Name != "" {
+		register(&l)
+		return &l
 	}
 
 	// first logger as DefaultLog
@@ -115,44 +165,54 @@ func (l *LevelLogger) Log(level int, depth int, prefix string, v ...interface{})
 	if len(v) == 0 {
 		return
 	}
-	if level >= l.Level {
-		var tag, color, message string
-		switch level {
-		case LevelDebug:
-			tag = tagDebug
-			color = colorDebug
-		case LevelInfo:
-			tag = tagInfo
-			color = colorInfo
-		case LevelWarn:
-			tag = tagWarn
-			color = colorWarn
-		case LevelError:
-			tag = tagError
-			color = colorError
-		case LevelFatal:
-			tag = tagFatal
-			color = colorFatal
-		default:
-			tag = tagLog
-			color = colorReset
-		}
-		if format, ok := v[0].(string); ok {
-			message = fmt.Sprintf(format, v[1:]...)
-		} else {
-			format := strings.Repeat("%+v ", len(v))
-			message = fmt.Sprintf(format, v...)
-		}
-		if l.Filename == "stdout" {
-			// XXX debug only, slow with 4 lock
-			l.mu.Lock()
-			l.Logger.SetPrefix(color)
-			l.Logger.Output(depth, fmt.Sprint(tag, " ", prefix, message, colorReset))
-			l.Logger.SetPrefix("")
-			l.mu.Unlock()
-		} else {
-			l.Logger.Output(depth, fmt.Sprint(tag, " ", prefix, message))
+	if level >= l.GetLevel() && l.sample(level, depth, formatKey(v)) {
+		var fields Fields
+		if level >= LevelError {
+			fields = stackFields(v)
 		}
+		l.output(level, depth+1, prefix, formatMessage(v), fields)
+	}
+}
+
+func formatMessage(v []interface{}) string {
+	if format, ok := v[0].(string); ok {
+		return fmt.Sprintf(format, v[1:]...)
+	}
+	format := strings.Repeat("%+v ", len(v))
+	return fmt.Sprintf(format, v...)
+}
+
+func (l *LevelLogger) output(level int, depth int, prefix string, message string, fields []Field) {
+	e := Entry{
+		Time:    now(),
+		Level:   level,
+		Prefix:  prefix,
+		Message: message,
+		Fields:  fields,
+	}
+	// Callers skip one more frame than Caller
+	pcs := [1]uintptr{}
+	if runtime.Callers(depth, pcs[:]) > 0 {
+		frame, _ := runtime.CallersFrames(pcs[:]).Next()
+		e.PC, e.File, e.Line = pcs[0], frame.File, frame.Line
+	}
+	l.out.write(&e)
+}
+
+func levelTag(level int) (tag string, color string) {
+	switch level {
+	case LevelDebug:
+		return tagDebug, colorDebug
+	case LevelInfo:
+		return tagInfo, colorInfo
+	case LevelWarn:
+		return tagWarn, colorWarn
+	case LevelError:
+		return tagError, colorError
+	case LevelFatal:
+		return tagFatal, colorFatal
+	default:
+		return tagLog, colorReset
 	}
 }
 
@@ -160,8 +220,43 @@ func (l *LevelLogger) SetPrefix(prefix string) {
 	l.Prefix = prefix
 }
 
+// safe to call while logging, replace of removed l.Level = level
 func (l *LevelLogger) SetLevel(level int) {
-	l.Level = level
+	atomic.StoreInt32(&l.level, int32(level))
+}
+
+// replace of removed l.Level
+func (l *LevelLogger) GetLevel() int {
+	return int(atomic.LoadInt32(&l.level))
+}
+
+// format of Install dest, other sinks keep their own
+func (l *LevelLogger) SetFormat(format int) {
+	l.Format = format
+	if l.sink != nil {
+		l.sink.Format = format
+	}
+}
+
+// write level and above to sink too
+func (l *LevelLogger) AddSink(level int, sink Sink) {
+	l.out.add(level, sink)
+}
+
+// MaxSize is counted in unit of RotateSizeKB/MB/GB, ignored by time rotate
+func (l *LevelLogger) SetRotate(rotate int, maxsize int, maxbackup int) {
+	l.Rotate = rotate
+	l.MaxSize = maxsize
+	l.MaxBackup = maxbackup
+}
+
+// close all sinks, stdout is not closed
+func (l *LevelLogger) Close() error {
+	if s := l.sampler; s != nil {
+		s.close()
+		s.flush(l)
+	}
+	return l.out.close()
 }
 
 func (l *LevelLogger) Debug(v ...interface{}) {
@@ -182,7 +277,9 @@ func (l *LevelLogger) Error(v ...interface{}) {
 
 func (l *LevelLogger) Fatal(v ...interface{}) {
 	l.Log(LevelFatal, 3, l.Prefix, v...)
-	os.Exit(1)
+	// l may be not DefaultLog or named
+	l.Sync()
+	Exit(1)
 }
 
 func Debug(v ...interface{}) {
@@ -213,7 +310,7 @@ func Fatal(v ...interface{}) {
 	if DefaultLog != nil {
 		DefaultLog.Log(LevelFatal, 3, DefaultLog.Prefix, v...)
 	}
-	os.Exit(1)
+	Exit(1)
 }
 
 func Debugd(depth int, v ...interface{}) {
@@ -244,5 +341,5 @@ func Fatald(depth int, v ...interface{}) {
 	if DefaultLog != nil {
 		DefaultLog.Log(LevelFatal, 3+depth, DefaultLog.Prefix, v...)
 	}
-	os.Exit(1)
+	Exit(1)
 }
@@ -1,7 +1,20 @@
 package log
 
+import "bytes"
+import "context"
+import "encoding/json"
 import "errors"
+import "fmt"
+import "io/ioutil"
+import "log/slog"
+import stdlog "log"
+import "os"
+import "os/exec"
+import "path/filepath"
+import "strings"
+import "sync"
 import "testing"
+import "time"
 
 func TestInstall(t *testing.T) {
 	Install("stdout")
@@ -44,3 +57,644 @@ func TestPrefix(t *testing.T) {
 	log.SetPrefix("")
 	log.Debug("test")
 }
+
+func TestRotateTime(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	base := time.Date(2019, 2, 20, 23, 59, 0, 0, time.Local)
+	now = func() time.Time { return base }
+	defer func() { now = time.Now }()
+
+	filename := filepath.Join(dir, "test.log")
+	log := Install(filename)
+	log.SetRotate(RotateTimeDay, 0, 2)
+	defer log.Close()
+
+	for i := 0; i < 4; i++ {
+		var wg sync.WaitGroup
+		for j := 0; j < 10; j++ {
+			wg.Add(1)
+			go func() {
+				log.Info("rotate %d", i)
+				wg.Done()
+			}()
+		}
+		wg.Wait()
+		base = base.Add(24 * time.Hour)
+	}
+	log.Info("rotate end")
+
+	files, _ := filepath.Glob(filename + ".*")
+	if len(files) != 2 {
+		t.Errorf("backup count %d != 2: %v", len(files), files)
+	}
+	for _, f := range files {
+		data, _ := ioutil.ReadFile(f)
+		if n := strings.Count(string(data), "\n"); n != 10 {
+			t.Errorf("%s line count %d != 10", f, n)
+		}
+	}
+	if _, err := os.Stat(filename + ".20190223"); err != nil {
+		t.Errorf("newest backup missing: %v", err)
+	}
+}
+
+func TestRotateSize(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "test.log")
+	log := Install(filename)
+	log.SetRotate(RotateSizeKB, 1, 3)
+	defer log.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			for j := 0; j < 20; j++ {
+				log.Info("rotate size %d %d", i, j)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, name := range []string{filename, filename + ".1", filename + ".2", filename + ".3"} {
+		info, err := os.Stat(name)
+		if err != nil {
+			t.Errorf("logfile missing: %v", err)
+			continue
+		}
+		if info.Size() > 1024 {
+			t.Errorf("%s size %d > 1KB", name, info.Size())
+		}
+	}
+	if _, err := os.Stat(filename + ".4"); err == nil {
+		t.Errorf("backup beyond MaxBackup not removed")
+	}
+}
+
+func TestFields(t *testing.T) {
+	Install("stdout")
+	Infow("", "ep", "mysql", "time", 10)
+	Infow("message", "sql", "select * from t where a=1 or b='|'", "err", nil)
+	Warnw("odd", "key")
+	Errorw("fields", Fields{{"a", 1}}, Field{"b", errors.New("error")})
+
+	cases := []struct {
+		message string
+		kv      []interface{}
+		expect  string
+	}{
+		{"", []interface{}{"ep", "mysql", "time", 10}, "ep=mysql|time=10"},
+		{"msg", []interface{}{"a", "x|y=z", "b", nil}, `msg|a=x\|y\=z|b=`},
+		{"msg", []interface{}{"a"}, "msg|!BADKEY=a"},
+		{"", []interface{}{Fields{{"b", 2}, {"a", 1}}, "c", 3}, "b=2|a=1|c=3"},
+	}
+	for _, c := range cases {
+		if s := formatFields(c.message, KV(c.kv...)); s != c.expect {
+			t.Errorf("formatFields %q != %q", s, c.expect)
+		}
+	}
+
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.log")
+	log := Install(filename)
+	log.Infow("caller", "k", "v")
+	log.Close()
+	data, _ := ioutil.ReadFile(filename)
+	if !strings.Contains(string(data), "log_test.go") || !strings.Contains(string(data), "[I] caller|k=v") {
+		t.Errorf("wrong output: %s", data)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	log := Install("stdout", WithFormat(FormatJSON))
+	log.Info("json %s", "format")
+	log.Infow("json", "ep", "mysql", "time", 10, "err", errors.New("error"), "msg", "dup")
+
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.log")
+	log = Install(filename, WithFormat(FormatJSON))
+	log.SetPrefix("prefix:")
+	log.Warnw("json", "ep", "mysql", "time", 10, "msg", "dup")
+	log.Close()
+
+	data, _ := ioutil.ReadFile(filename)
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("not json: %s %v", data, err)
+	}
+	expect := map[string]interface{}{
+		"level": "W", "prefix": "prefix:", "msg": "json",
+		"ep": "mysql", "time": float64(10), "_msg": "dup",
+	}
+	for k, v := range expect {
+		if m[k] != v {
+			t.Errorf("key %s: %v != %v", k, m[k], v)
+		}
+	}
+	if !strings.HasPrefix(m["file"].(string), "log_test.go:") {
+		t.Errorf("wrong file: %v", m["file"])
+	}
+	if strings.Contains(string(data), "\033[") {
+		t.Errorf("color in json: %q", data)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	Install("stdout")
+	if Get("notexist") != DefaultLog {
+		t.Errorf("Get should fallback to DefaultLog")
+	}
+
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "sql.log")
+	sqllog := Install(filename, WithName("sql"))
+	defer sqllog.Close()
+	if DefaultLog == sqllog {
+		t.Errorf("named logger should not be DefaultLog")
+	}
+	sqllog.SetLevel(LevelWarn)
+	Get("sql").Info("info to sql")
+	Get("sql").Warn("warn to sql")
+
+	data, _ := ioutil.ReadFile(filename)
+	if strings.Contains(string(data), "info to sql") || !strings.Contains(string(data), "warn to sql") {
+		t.Errorf("wrong output: %s", data)
+	}
+
+	level := DefaultLog.GetLevel()
+	web := DefaultLog.Named("web")
+	web.SetLevel(LevelError)
+	Get("web").Warn("not print")
+	Get("web").Error("print")
+	if Get("web") != web || DefaultLog.GetLevel() != level {
+		t.Errorf("named logger should has its own level")
+	}
+}
+
+type testSink struct {
+	entries []*Entry
+	closed  bool
+}
+
+func (s *testSink) Write(e *Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestSink(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "app.log")
+	log := Install(filename)
+
+	errlog, err := OpenSink(filepath.Join(dir, "app.error.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.AddSink(LevelWarn, errlog)
+	log.AddSink(LevelInfo, NewWriterSink(os.Stdout, FormatText, true))
+	custom := &testSink{}
+	log.AddSink(LevelError, custom)
+
+	log.Debug("debug")
+	log.Info("info")
+	log.Warnw("warn", "k", "v")
+	log.Error("error")
+	// stdlib api go through sinks too
+	log.Printf("printf %d", 1)
+	log.Close()
+
+	data, _ := ioutil.ReadFile(filename)
+	if n := strings.Count(string(data), "\n"); n != 5 || !strings.Contains(string(data), "[I] printf 1") {
+		t.Errorf("wrong app.log: %s", data)
+	}
+	data, _ = ioutil.ReadFile(filepath.Join(dir, "app.error.log"))
+	if n := strings.Count(string(data), "\n"); n != 2 || !strings.Contains(string(data), "[W] warn|k=v") {
+		t.Errorf("wrong app.error.log: %s", data)
+	}
+	if len(custom.entries) != 1 || custom.entries[0].Message != "error" || !custom.closed {
+		t.Errorf("wrong custom sink: %+v", custom)
+	}
+}
+
+type chanSink chan *Entry
+
+// drop if full, never block other tests
+func (s chanSink) Write(e *Entry) error {
+	select {
+	case s <- e:
+	default:
+	}
+	return nil
+}
+
+func (s chanSink) Close() error {
+	return nil
+}
+
+type slowSink struct {
+	testSink
+	mu    sync.Mutex
+	block chan struct{}
+}
+
+func (s *slowSink) Write(e *Entry) error {
+	<-s.block
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.testSink.Write(e)
+}
+
+func TestAsync(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "async.log")
+	log := Install(filename, WithAsync(16, AsyncBlock))
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			for j := 0; j < 100; j++ {
+				log.Info("async %d %d", i, j)
+			}
+			wg.Done()
+		}(i)
+	}
+	wg.Wait()
+	log.Sync()
+	data, _ := ioutil.ReadFile(filename)
+	if n := strings.Count(string(data), "\n"); n != 1000 {
+		t.Errorf("line count %d != 1000", n)
+	}
+	log.Close()
+
+	for _, policy := range []int{AsyncDropOldest, AsyncDropNewest} {
+		log = Install("stdout", WithAsync(4, policy))
+		sink := &slowSink{block: make(chan struct{})}
+		log.AddSink(0, sink)
+
+		// first entry is taken by flusher and blocked in sink
+		log.Info("block")
+		q := log.out.async
+		q.mu.Lock()
+		for q.count > 0 {
+			q.changed.Wait()
+		}
+		q.mu.Unlock()
+		for i := 0; i < 10; i++ {
+			log.Info("drop %d", i)
+		}
+		close(sink.block)
+		log.Close()
+
+		if log.Dropped() != 6 || len(sink.entries) != 5 {
+			t.Errorf("policy %d dropped %d entries %d", policy, log.Dropped(), len(sink.entries))
+		}
+		expect := "drop 3"
+		if policy == AsyncDropOldest {
+			expect = "drop 9"
+		}
+		if last := sink.entries[len(sink.entries)-1].Message; last != expect {
+			t.Errorf("policy %d last entry %s != %s", policy, last, expect)
+		}
+	}
+}
+
+// Fatal exit the process, so run it in a child test process
+func TestFatalSync(t *testing.T) {
+	if filename := os.Getenv("ZGO_FATAL_LOG"); filename != "" {
+		Install("stdout")
+		// not DefaultLog and not named
+		log := Install(filename, WithAsync(16, AsyncBlock))
+		for i := 0; i < 10000; i++ {
+			log.Info("async %d", i)
+		}
+		log.Fatal("fatal")
+		return
+	}
+
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "fatal.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalSync$")
+	cmd.Env = append(os.Environ(), "ZGO_FATAL_LOG="+filename)
+	if err := cmd.Run(); err == nil {
+		t.Errorf("Fatal not exit")
+	}
+
+	data, _ := ioutil.ReadFile(filename)
+	if n := strings.Count(string(data), "\n"); n != 10001 || !strings.Contains(string(data), "[F] fatal") {
+		t.Errorf("lines lost before exit: %d", n)
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "abc")
+	if RequestID(ctx) != "abc" || RequestID(context.Background()) != "" {
+		t.Errorf("wrong request id")
+	}
+
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.log")
+	log := Install(filename)
+	log.InfoCtx(ctx, "with %s", "rid")
+	log.InfoCtx(context.Background(), "without rid")
+	log.Infow("", ContextFields(ctx), "ep", "mysql")
+	log.Close()
+
+	data, _ := ioutil.ReadFile(filename)
+	for _, s := range []string{"[I] with rid|rid=abc\n", "[I] without rid\n", "[I] rid=abc|ep=mysql\n"} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("%q not in output: %s", s, data)
+		}
+	}
+}
+
+func TestChangeLevel(t *testing.T) {
+	for s, expect := range map[string]int{"debug": LevelDebug, "I": LevelInfo, " Warn ": LevelWarn, "e": LevelError} {
+		if level, err := ParseLevel(s); err != nil || level != expect {
+			t.Errorf("ParseLevel %s: %d %v", s, level, err)
+		}
+	}
+	if _, err := ParseLevel("unknown"); err == nil {
+		t.Errorf("ParseLevel should fail")
+	}
+	if StepLevel(LevelDebug, -1) != LevelDebug || StepLevel(LevelInfo, 1) != LevelWarn || StepLevel(LevelFatal, 1) != LevelFatal {
+		t.Errorf("wrong StepLevel")
+	}
+
+	log := Install("stdout", WithName("level"))
+	log.ChangeLevel(LevelError, "test")
+	if Lookup("level") != log || Levels()["level"] != "error" {
+		t.Errorf("wrong Levels: %v", Levels())
+	}
+}
+
+func TestColor(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+	fp, _ := os.Create(filepath.Join(dir, "color.log"))
+	defer fp.Close()
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	if useColor(ColorAuto, fp) || !useColor(ColorAlways, fp) || useColor(ColorNever, os.Stdout) {
+		t.Errorf("wrong color for file")
+	}
+	t.Setenv("FORCE_COLOR", "1")
+	if !useColor(ColorAuto, fp) {
+		t.Errorf("FORCE_COLOR not work")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if useColor(ColorAuto, fp) || !useColor(ColorAlways, fp) {
+		t.Errorf("NO_COLOR not work")
+	}
+
+	filename := filepath.Join(dir, "test.log")
+	log := Install(filename, WithColor(ColorAlways))
+	log.Info("color")
+	log.Close()
+	data, _ := ioutil.ReadFile(filename)
+	if !strings.HasPrefix(string(data), colorInfo) {
+		t.Errorf("no color in file: %q", data)
+	}
+
+	log = Install("stderr", WithColor(ColorNever))
+	log.Info("stderr without color")
+}
+
+func TestSampling(t *testing.T) {
+	base := time.Date(2019, 2, 20, 0, 0, 0, 0, time.Local)
+	now = func() time.Time { return base }
+	defer func() { now = time.Now }()
+
+	log := Install("stdout", WithName("sample"), WithSampling(SampleConf{
+		By: SampleByFormat, First: 3, Thereafter: 5, Report: time.Minute,
+	}))
+	sink := &testSink{}
+	log.AddSink(0, sink)
+
+	for i := 0; i < 20; i++ {
+		log.Info("hot %d", i)
+		log.Warn("warn %d", i)
+	}
+	// 3 first, then 8th 13th 18th
+	count := map[int]int{}
+	for _, e := range sink.entries {
+		count[e.Level]++
+	}
+	if count[LevelInfo] != 6 || count[LevelWarn] != 20 {
+		t.Errorf("wrong sampled count: %v", count)
+	}
+	log.Info("cold")
+
+	// new second reset counter, new minute report dropped and evict cold key
+	base = base.Add(time.Minute)
+	sink.entries = nil
+	log.Info("hot %d", 100)
+	if len(sink.entries) != 2 || sink.entries[0].Message != "log sampled" || sink.entries[1].Message != "hot 100" {
+		t.Fatalf("wrong summary: %+v", sink.entries)
+	}
+	if s := formatFields("", sink.entries[0].Fields); s != "key=hot %d|dropped=14" {
+		t.Errorf("wrong summary: %s", s)
+	}
+	if _, ok := log.sampler.counters["cold"]; ok || len(log.sampler.counters) != 1 {
+		t.Errorf("counters not evicted: %v", log.sampler.counters)
+	}
+
+	// dropped after last summary is written by Sync
+	for i := 0; i < 5; i++ {
+		log.Info("tail %d", i)
+	}
+	sink.entries = nil
+	log.Sync()
+	if len(sink.entries) != 1 || formatFields(sink.entries[0].Message, sink.entries[0].Fields) != "log sampled|key=tail %d|dropped=2" {
+		t.Errorf("no summary by sync: %+v", sink.entries)
+	}
+	log.SetSampling(nil)
+}
+
+func TestSampleReport(t *testing.T) {
+	log := Install("stdout", WithName("report"), WithSampling(SampleConf{
+		By: SampleByFormat, First: 1, Report: 10 * time.Millisecond,
+	}))
+	sink := make(chanSink, 10)
+	log.AddSink(0, sink)
+
+	// summary by ticker without more lines
+	for i := 0; i < 3; i++ {
+		log.Info("hot")
+	}
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case e := <-sink:
+			if e.Message != "log sampled" {
+				continue
+			}
+			if s := formatFields("", e.Fields); s != "key=hot|dropped=2" {
+				t.Errorf("wrong summary: %s", s)
+			}
+		case <-timeout:
+			t.Errorf("no summary by ticker")
+		}
+		break
+	}
+	log.Close()
+}
+
+func TestSlog(t *testing.T) {
+	log := Install("stdout", WithName("slog"))
+	log.SetLevel(LevelInfo)
+	sink := &testSink{}
+	log.AddSink(0, sink)
+
+	logger := slog.New(NewSlogHandler(log))
+	logger.Debug("not print")
+	logger.With("ep", "mysql").WithGroup("g").Info("slog", "a", 1, slog.Group("b", "c", 2))
+	logger.InfoContext(WithRequestID(context.Background(), "abc"), "ctx")
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("wrong entries count %d", len(sink.entries))
+	}
+	e := sink.entries[0]
+	if s := formatFields(e.Message, e.Fields); s != "slog|ep=mysql|g.a=1|g.b.c=2" {
+		t.Errorf("wrong slog output: %s", s)
+	}
+	if !strings.HasSuffix(e.File, "log_test.go") {
+		t.Errorf("wrong caller: %s", e.File)
+	}
+	if s := formatFields(sink.entries[1].Message, sink.entries[1].Fields); s != "ctx|rid=abc" {
+		t.Errorf("wrong slog ctx output: %s", s)
+	}
+
+	buf := bytes.Buffer{}
+	log = NewFromSlog(slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: true}))
+	log.SetPrefix("prefix:")
+	log.Infow("from zgo", "k", "v")
+	log.Debug("not print")
+	if s := buf.String(); !strings.Contains(s, "level=INFO") || !strings.Contains(s, `msg="prefix:from zgo" k=v`) || !strings.Contains(s, "log_test.go") {
+		t.Errorf("wrong slog output: %s", s)
+	}
+}
+
+func TestStdlog(t *testing.T) {
+	log := Install("stdout", WithName("stdlog"), WithStdlog(LevelWarn))
+	sink := &testSink{}
+	log.AddSink(0, sink)
+	defer stdlog.SetOutput(os.Stderr)
+
+	stdlog.Printf("from stdlib %d", 1)
+	NewStdLogger(log, LevelError).Println("error log")
+
+	if len(sink.entries) != 2 {
+		t.Fatalf("wrong entries count %d", len(sink.entries))
+	}
+	e := sink.entries[0]
+	if e.Level != LevelWarn || e.Message != "from stdlib 1" || e.File != "log_test.go" || e.Line == 0 {
+		t.Errorf("wrong stdlog entry: %+v", e)
+	}
+	if e = sink.entries[1]; e.Level != LevelError || e.Message != "error log" {
+		t.Errorf("wrong stdlog entry: %+v", e)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	Install("stdout")
+	sink := make(chanSink, 2)
+	DefaultLog.AddSink(LevelError, sink)
+
+	Go(func() {
+		panic("test panic")
+	}, false)
+	e := <-sink
+	if e.Message != "panic: test panic" || !strings.HasSuffix(e.File, "log_test.go") || !strings.Contains(e.Fields[0].Value.(string), "TestRecover") {
+		t.Errorf("wrong panic entry: %+v", e)
+	}
+
+	// runtime error panic from runtime.sigpanic
+	Go(func() {
+		var m map[string]int
+		m["nil"] = 1
+	}, false)
+	e = <-sink
+	if !strings.HasPrefix(e.Message, "panic: assignment to entry in nil map") || !strings.HasSuffix(e.File, "log_test.go") {
+		t.Errorf("wrong runtime panic entry: %+v", e)
+	}
+
+	func() {
+		defer func() {
+			if r := recover(); r != "repanic" {
+				t.Errorf("not repanic: %v", r)
+			}
+		}()
+		defer Recover(true)
+		panic("repanic")
+	}()
+	if e = <-sink; e.Message != "panic: repanic" {
+		t.Errorf("wrong panic entry: %+v", e)
+	}
+}
+
+var errBase = errors.New("base error")
+
+func findUser() error {
+	return NewError("find user: %w", errBase)
+}
+
+func TestStackError(t *testing.T) {
+	err := WrapError(findUser(), "login")
+	if err.Error() != "login: find user: base error" {
+		t.Errorf("wrong message: %s", err)
+	}
+	var se *StackError
+	if !errors.Is(err, errBase) || !errors.As(err, &se) {
+		t.Errorf("errors.Is/As not work")
+	}
+	if WrapError(nil, "nil") != nil {
+		t.Errorf("wrap nil should be nil")
+	}
+
+	stack := ErrorStack(err)
+	if strings.Count(stack, "TestStackError") != 2 || !strings.Contains(stack, "caused by: find user: base error\ngithub.com/JoveYu/zgo/log.findUser") {
+		t.Errorf("wrong stack: %s", stack)
+	}
+	if s := fmt.Sprintf("%v", err); s != err.Error() {
+		t.Errorf("wrong %%v: %s", s)
+	}
+	if s := fmt.Sprintf("%+v", err); s != stack {
+		t.Errorf("wrong %%+v: %s", s)
+	}
+
+	log := Install("stdout", WithName("stack"))
+	sink := &testSink{}
+	log.AddSink(0, sink)
+	log.Warn("warn %v", err)
+	log.Error("error %v", err)
+	log.Errorw("errorw", "err", err)
+	if len(sink.entries[0].Fields) != 0 {
+		t.Errorf("stack should not print for warn")
+	}
+	for _, e := range sink.entries[1:] {
+		if len(e.Fields) == 0 || e.Fields[len(e.Fields)-1].Value != stack {
+			t.Errorf("stack not print: %+v", e)
+		}
+	}
+}
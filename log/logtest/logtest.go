@@ -0,0 +1,125 @@
+// capture log in test, output go through t.Log and show only when test fail
+//
+// func TestXXX(t *testing.T) {
+//     l := logtest.New(t)
+//     ...
+//     l.AssertLogged(log.LevelInfo, "ep=mysql")
+// }
+//
+// DefaultLog is replaced until test end, do not use with t.Parallel
+
+package logtest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/JoveYu/zgo/log"
+)
+
+type Logger struct {
+	*log.LevelLogger
+	t testing.TB
+
+	mu      sync.Mutex
+	entries []log.Entry
+	done    bool
+}
+
+// install as DefaultLog, restore old one by t.Cleanup
+func New(t testing.TB) *Logger {
+	l := Logger{t: t}
+	l.LevelLogger = log.NewWithSink(&l)
+
+	old := log.DefaultLog
+	log.DefaultLog = l.LevelLogger
+	t.Cleanup(func() {
+		l.mu.Lock()
+		l.done = true
+		l.mu.Unlock()
+		log.DefaultLog = old
+	})
+	return &l
+}
+
+func (l *Logger) Write(e *log.Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, *e)
+	// t.Log panic after test end
+	if !l.done {
+		l.t.Logf("%s %s:%d: %s", levelTag(e.Level), shortFile(e.File), e.Line, Text(e))
+	}
+	return nil
+}
+
+func (l *Logger) Close() error {
+	return nil
+}
+
+// copy of all captured entries
+func (l *Logger) Entries() []log.Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]log.Entry{}, l.entries...)
+}
+
+func (l *Logger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+}
+
+// entries at level contain substring in message or fields
+func (l *Logger) Find(level int, substring string) []log.Entry {
+	var found []log.Entry
+	for _, e := range l.Entries() {
+		if e.Level == level && strings.Contains(Text(&e), substring) {
+			found = append(found, e)
+		}
+	}
+	return found
+}
+
+func (l *Logger) AssertLogged(level int, substring string) {
+	l.t.Helper()
+	if len(l.Find(level, substring)) == 0 {
+		l.t.Errorf("no %s log contains %q", log.LevelName(level), substring)
+	}
+}
+
+func (l *Logger) AssertNotLogged(level int, substring string) {
+	l.t.Helper()
+	if found := l.Find(level, substring); len(found) > 0 {
+		l.t.Errorf("%s log contains %q: %s", log.LevelName(level), substring, Text(&found[0]))
+	}
+}
+
+// message with fields, same as text output without escape
+func Text(e *log.Entry) string {
+	sb := strings.Builder{}
+	sb.WriteString(e.Prefix)
+	sb.WriteString(e.Message)
+	for i, f := range e.Fields {
+		if i > 0 || e.Message != "" {
+			sb.WriteByte('|')
+		}
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		sb.WriteString(fmt.Sprint(f.Value))
+	}
+	return sb.String()
+}
+
+func levelTag(level int) string {
+	return "[" + strings.ToUpper(log.LevelName(level)[:1]) + "]"
+}
+
+func shortFile(file string) string {
+	if idx := strings.LastIndexByte(file, '/'); idx >= 0 {
+		return file[idx+1:]
+	}
+	return file
+}
@@ -0,0 +1,56 @@
+package logtest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/JoveYu/zgo/log"
+)
+
+func TestLogger(t *testing.T) {
+	old := log.DefaultLog
+
+	t.Run("capture", func(t *testing.T) {
+		l := New(t)
+		if log.DefaultLog != l.LevelLogger {
+			t.Fatalf("DefaultLog not replaced")
+		}
+
+		log.Info("hello %s", "world")
+		log.Get("notexist").Warnw("slow", "cost", 3)
+		log.Errorw("fail", "err", errors.New("boom"))
+
+		entries := l.Entries()
+		if len(entries) != 3 {
+			t.Fatalf("entries: %d", len(entries))
+		}
+		if entries[1].Fields[0].Key != "cost" || entries[1].Fields[0].Value != 3 {
+			t.Errorf("fields: %v", entries[1].Fields)
+		}
+		l.AssertLogged(log.LevelInfo, "hello world")
+		l.AssertLogged(log.LevelWarn, "slow|cost=3")
+		l.AssertLogged(log.LevelError, "err=boom")
+		l.AssertNotLogged(log.LevelError, "hello")
+
+		l.Reset()
+		if len(l.Entries()) != 0 {
+			t.Errorf("not reset")
+		}
+	})
+
+	if log.DefaultLog != old {
+		t.Errorf("DefaultLog not restored")
+	}
+}
+
+func TestFind(t *testing.T) {
+	l := New(t)
+	log.Debug("only debug")
+
+	if found := l.Find(log.LevelInfo, "only debug"); len(found) != 0 {
+		t.Errorf("level not match: %v", found)
+	}
+	if found := l.Find(log.LevelDebug, "only"); len(found) != 1 {
+		t.Errorf("find: %v", found)
+	}
+}
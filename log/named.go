@@ -0,0 +1,72 @@
+// named logger for package
+// log.Install("sql.log", log.WithName("sql"))
+// log.Get("sql").Info("...")
+
+package log
+
+import (
+	"io"
+	"log"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	loggers   = map[string]*LevelLogger{}
+	loggersMu sync.RWMutex
+
+	// used by Get before any Install
+	discardLog = &LevelLogger{
+		Logger: log.New(io.Discard, "", 0),
+		out:    &fanout{},
+		level:  LevelFatal,
+	}
+)
+
+func register(l *LevelLogger) {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+	loggers[l.Name] = l
+}
+
+// get named logger, fallback to DefaultLog if name has no own setting
+func Get(name string) *LevelLogger {
+	loggersMu.RLock()
+	l, ok := loggers[name]
+	loggersMu.RUnlock()
+	if ok {
+		return l
+	}
+	if DefaultLog != nil {
+		return DefaultLog
+	}
+	return discardLog
+}
+
+// all named loggers
+func Loggers() map[string]*LevelLogger {
+	loggersMu.RLock()
+	defer loggersMu.RUnlock()
+	m := make(map[string]*LevelLogger, len(loggers))
+	for k, v := range loggers {
+		m[k] = v
+	}
+	return m
+}
+
+// named logger share sinks with l, but has its own level and prefix
+// rotate and Close still belong to l
+func (l *LevelLogger) Named(name string) *LevelLogger {
+	n := LevelLogger{
+		Logger:   l.Logger,
+		out:      l.out,
+		sink:     l.sink,
+		Name:     name,
+		Prefix:   l.Prefix,
+		Filename: l.Filename,
+		level:    atomic.LoadInt32(&l.level),
+		Format:   l.Format,
+	}
+	register(&n)
+	return &n
+}
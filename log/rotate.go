@@ -0,0 +1,245 @@
+// file writer with rotate support
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// for test
+var now = time.Now
+
+// rotate setting, shared by LevelLogger and file writer
+type RotateConf struct {
+	Rotate    int
+	MaxSize   int
+	MaxBackup int
+}
+
+type fileWriter struct {
+	mu       sync.Mutex
+	fp       *os.File
+	filename string
+	conf     *RotateConf
+
+	// start of current time period, zero if not time rotate
+	period time.Time
+	// bytes in current file
+	size int64
+}
+
+func newFileWriter(filename string, conf *RotateConf) (*fileWriter, error) {
+	w := fileWriter{
+		filename: filename,
+		conf:     conf,
+	}
+	err := w.open()
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (w *fileWriter) open() error {
+	fp, err := os.OpenFile(w.filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	w.fp = fp
+
+	// old file belongs to the period it was last written
+	w.period = time.Time{}
+	w.size = 0
+	if info, err := fp.Stat(); err == nil && info.Size() > 0 {
+		w.period = info.ModTime()
+		w.size = info.Size()
+	}
+	return nil
+}
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fp == nil {
+		return 0, os.ErrClosed
+	}
+
+	if isTimeRotate(w.conf.Rotate) {
+		t := now()
+		start := periodStart(w.conf.Rotate, t)
+		if w.period.IsZero() {
+			w.period = start
+		} else if old := periodStart(w.conf.Rotate, w.period); old.Before(start) {
+			w.rotateTime(old)
+		}
+		w.period = start
+	} else if max := maxSize(w.conf.Rotate, w.conf.MaxSize); max > 0 {
+		if w.size > 0 && w.size+int64(len(p)) > max {
+			w.rotateSize()
+		}
+	}
+
+	// reopen may fail after rotate
+	if w.fp == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := w.fp.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *fileWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fp == nil {
+		return nil
+	}
+	return w.fp.Sync()
+}
+
+func (w *fileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.fp == nil {
+		return nil
+	}
+	err := w.fp.Close()
+	w.fp = nil
+	return err
+}
+
+// rename current file with time suffix and reopen
+func (w *fileWriter) rotateTime(period time.Time) {
+	backup := w.filename + "." + period.Format(timeSuffix(w.conf.Rotate))
+
+	w.fp.Close()
+	err := os.Rename(w.filename, backup)
+	if err != nil {
+		os.Stderr.WriteString("can not rotate logfile: " + err.Error() + "\n")
+	}
+	err = w.open()
+	if err != nil {
+		w.fp = nil
+		os.Stderr.WriteString("can not open logfile: " + err.Error() + "\n")
+		return
+	}
+
+	if w.conf.MaxBackup > 0 {
+		w.removeBackup(len(timeSuffix(w.conf.Rotate)))
+	}
+}
+
+// rename name.N to name.N+1, current file to name.1 and reopen
+func (w *fileWriter) rotateSize() {
+	last := w.conf.MaxBackup
+	if last <= 0 {
+		// keep all backup
+		for last = 1; ; last++ {
+			if _, err := os.Stat(w.backupName(last)); err != nil {
+				break
+			}
+		}
+	} else {
+		os.Remove(w.backupName(last))
+	}
+	for i := last - 1; i > 0; i-- {
+		os.Rename(w.backupName(i), w.backupName(i+1))
+	}
+
+	w.fp.Close()
+	err := os.Rename(w.filename, w.backupName(1))
+	if err != nil {
+		os.Stderr.WriteString("can not rotate logfile: " + err.Error() + "\n")
+	}
+	err = w.open()
+	if err != nil {
+		w.fp = nil
+		os.Stderr.WriteString("can not open logfile: " + err.Error() + "\n")
+	}
+}
+
+func (w *fileWriter) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", w.filename, n)
+}
+
+// remove oldest backup files beyond MaxBackup
+func (w *fileWriter) removeBackup(suffixLen int) {
+	files, err := filepath.Glob(w.filename + ".*")
+	if err != nil {
+		return
+	}
+
+	var backups []string
+	for _, f := range files {
+		suffix := f[len(w.filename)+1:]
+		if len(suffix) == suffixLen && isDigit(suffix) {
+			backups = append(backups, f)
+		}
+	}
+	if len(backups) <= w.conf.MaxBackup {
+		return
+	}
+
+	// time suffix sort as string
+	sort.Strings(backups)
+	for _, f := range backups[:len(backups)-w.conf.MaxBackup] {
+		os.Remove(f)
+	}
+}
+
+func isTimeRotate(rotate int) bool {
+	return rotate >= RotateTimeDay && rotate <= RotateTimeSecond
+}
+
+// max file size in bytes, 0 if not size rotate
+func maxSize(rotate int, size int) int64 {
+	switch rotate {
+	case RotateSizeKB:
+		return int64(size) << 10
+	case RotateSizeMB:
+		return int64(size) << 20
+	case RotateSizeGB:
+		return int64(size) << 30
+	default:
+		return 0
+	}
+}
+
+func timeSuffix(rotate int) string {
+	switch rotate {
+	case RotateTimeDay:
+		return "20060102"
+	case RotateTimeHour:
+		return "2006010215"
+	case RotateTimeMinute:
+		return "200601021504"
+	default:
+		return "20060102150405"
+	}
+}
+
+func periodStart(rotate int, t time.Time) time.Time {
+	switch rotate {
+	case RotateTimeDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case RotateTimeHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case RotateTimeMinute:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	}
+}
+
+func isDigit(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,196 @@
+// sampling for hot log lines, Warn and above are never sampled
+// log.Install("app.log", log.WithSampling(log.SampleConf{First: 100, Thereafter: 10}))
+// every key log first 100 lines per second, then 1 in 10
+// dropped lines are counted and reported in summary line
+// summary is written every Report, and by Sync and Close for the rest
+
+package log
+
+import (
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SampleByCaller = iota
+	SampleByFormat
+)
+
+type SampleConf struct {
+	// SampleByCaller or SampleByFormat, empty format use caller
+	By int
+	// first N lines per second for each key
+	First int
+	// then 1 in Thereafter, 0 drop all
+	Thereafter int
+	// summary interval, default 1 minute
+	Report time.Duration
+}
+
+type sampleCounter struct {
+	second  int64
+	n       int
+	dropped uint64
+}
+
+type sampler struct {
+	mu       sync.Mutex
+	conf     SampleConf
+	counters map[string]*sampleCounter
+	report   time.Time
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// summary of l is written every Report until stop
+func newSampler(l *LevelLogger, conf SampleConf) *sampler {
+	if conf.Report <= 0 {
+		conf.Report = time.Minute
+	}
+	s := &sampler{
+		conf:     conf,
+		counters: map[string]*sampleCounter{},
+		report:   now(),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run(l)
+	return s
+}
+
+func (s *sampler) run(l *LevelLogger) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.conf.Report)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush(l)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// stop ticker and wait it exit, summary is not written
+func (s *sampler) close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	<-s.done
+}
+
+// write summary now, used by ticker, Sync and Close
+func (s *sampler) flush(l *LevelLogger) {
+	s.mu.Lock()
+	s.report = now()
+	summary := s.summary(s.report.Unix())
+	s.mu.Unlock()
+
+	for _, fields := range summary {
+		l.output(LevelInfo, 3, l.Prefix, "log sampled", fields)
+	}
+}
+
+func WithSampling(conf SampleConf) Option {
+	return func(l *LevelLogger) {
+		l.sampler = newSampler(l, conf)
+	}
+}
+
+// nil to disable sampling, summary of old conf is written
+func (l *LevelLogger) SetSampling(conf *SampleConf) {
+	if old := l.sampler; old != nil {
+		old.close()
+		old.flush(l)
+	}
+	if conf == nil {
+		l.sampler = nil
+	} else {
+		l.sampler = newSampler(l, *conf)
+	}
+}
+
+// false if the line should be dropped, depth is same as Log
+func (l *LevelLogger) sample(level int, depth int, format string) bool {
+	s := l.sampler
+	if s == nil || level >= LevelWarn {
+		return true
+	}
+
+	key := format
+	if s.conf.By == SampleByCaller || key == "" {
+		_, file, line, _ := runtime.Caller(depth)
+		key = filepath.Base(file) + ":" + strconv.Itoa(line)
+	}
+
+	t := now()
+	s.mu.Lock()
+	c, ok := s.counters[key]
+	if !ok {
+		c = &sampleCounter{}
+		s.counters[key] = c
+	}
+	if sec := t.Unix(); c.second != sec {
+		c.second = sec
+		c.n = 0
+	}
+	c.n++
+	allow := c.n <= s.conf.First ||
+		(s.conf.Thereafter > 0 && (c.n-s.conf.First)%s.conf.Thereafter == 0)
+	if !allow {
+		c.dropped++
+	}
+
+	var summary []Fields
+	if t.Sub(s.report) >= s.conf.Report {
+		s.report = t
+		summary = s.summary(t.Unix())
+	}
+	s.mu.Unlock()
+
+	for _, fields := range summary {
+		l.output(LevelInfo, depth+1, l.Prefix, "log sampled", fields)
+	}
+	return allow
+}
+
+// dropped count since last summary, sorted by key
+// keys not seen in current second are evicted, so counters not grow forever
+func (s *sampler) summary(sec int64) []Fields {
+	var keys []string
+	for k, c := range s.counters {
+		if c.dropped > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	summary := make([]Fields, 0, len(keys))
+	for _, k := range keys {
+		summary = append(summary, Fields{
+			{Key: "key", Value: k},
+			{Key: "dropped", Value: s.counters[k].dropped},
+		})
+		s.counters[k].dropped = 0
+	}
+	for k, c := range s.counters {
+		if c.second != sec {
+			delete(s.counters, k)
+		}
+	}
+	return summary
+}
+
+func formatKey(v []interface{}) string {
+	if len(v) == 0 {
+		return ""
+	}
+	format, _ := v[0].(string)
+	return format
+}
@@ -0,0 +1,31 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// SIGUSR1 is more verbose, SIGUSR2 is less verbose, one step each time
+// apply to DefaultLog and all named loggers, only the first call watch
+func WatchSignal() {
+	watchOnce.Do(func() {
+		c := make(chan os.Signal, 1)
+		signal.Notify(c, syscall.SIGUSR1, syscall.SIGUSR2)
+		go func() {
+			for sig := range c {
+				if sig == syscall.SIGUSR1 {
+					stepAll(-1, "SIGUSR1")
+				} else {
+					stepAll(1, "SIGUSR2")
+				}
+			}
+		}()
+	})
+}
+
+var watchOnce sync.Once
@@ -0,0 +1,44 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSignal(t *testing.T) {
+	log := Install("stdout", WithName("signal"))
+	log.SetLevel(LevelInfo)
+	sink := make(chanSink, 1)
+	log.AddSink(0, sink)
+	// second call should not step twice
+	WatchSignal()
+	WatchSignal()
+
+	wait := func() string {
+		select {
+		case e := <-sink:
+			return formatFields(e.Message, e.Fields)
+		case <-time.After(time.Second):
+			t.Fatal("level not changed")
+			return ""
+		}
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR2)
+	if s := wait(); s != "change log level|name=signal|from=info|to=warn|by=SIGUSR2" {
+		t.Errorf("wrong change by SIGUSR2: %s", s)
+	}
+
+	syscall.Kill(syscall.Getpid(), syscall.SIGUSR1)
+	if s := wait(); s != "change log level|name=signal|from=warn|to=info|by=SIGUSR1" {
+		t.Errorf("wrong change by SIGUSR1: %s", s)
+	}
+	if level := log.GetLevel(); level != LevelInfo {
+		t.Errorf("wrong level after signals: %d", level)
+	}
+	log.Close()
+}
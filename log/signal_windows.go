@@ -0,0 +1,5 @@
+package log
+
+// no SIGUSR1 and SIGUSR2 on windows
+func WatchSignal() {
+}
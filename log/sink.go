@@ -0,0 +1,199 @@
+// log destination, one LevelLogger can write to many sinks
+//
+// l := log.Install("app.log")
+// errlog, _ := log.OpenSink("app.error.log")
+// l.AddSink(log.LevelWarn, errlog)
+// l.AddSink(log.LevelInfo, log.NewWriterSink(os.Stdout, log.FormatText, true))
+
+package log
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+type Sink interface {
+	Write(e *Entry) error
+	Close() error
+}
+
+// Sink with its own min level
+type levelSink struct {
+	level int
+	sink  Sink
+}
+
+// sinks shared by LevelLogger and its Named logger
+type fanout struct {
+	mu    sync.RWMutex
+	sinks []levelSink
+	async *asyncQueue
+}
+
+func (f *fanout) add(level int, sink Sink) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sinks = append(f.sinks, levelSink{level: level, sink: sink})
+}
+
+func (f *fanout) write(e *Entry) {
+	if f.async != nil {
+		f.async.put(e)
+		return
+	}
+	f.writeSinks(e)
+}
+
+func (f *fanout) writeSinks(e *Entry) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, s := range f.sinks {
+		if e.Level >= s.level {
+			if err := s.sink.Write(e); err != nil {
+				os.Stderr.WriteString("can not write log: " + err.Error() + "\n")
+			}
+		}
+	}
+}
+
+// sinks like file can be flushed
+type syncer interface {
+	Sync() error
+}
+
+func (f *fanout) sync() {
+	if f.async != nil {
+		f.async.sync()
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, s := range f.sinks {
+		if s, ok := s.sink.(syncer); ok {
+			s.Sync()
+		}
+	}
+}
+
+func (f *fanout) close() error {
+	if f.async != nil {
+		f.async.close()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var err error
+	for _, s := range f.sinks {
+		if e := s.sink.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// write text or json to io.Writer, one Write for one line
+type WriterSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	Format int
+	Color  bool
+}
+
+// w is not closed by Close
+func NewWriterSink(w io.Writer, format int, color bool) *WriterSink {
+	return &WriterSink{
+		w:      w,
+		Format: format,
+		Color:  color,
+	}
+}
+
+func (s *WriterSink) Write(e *Entry) error {
+	var data []byte
+	if s.Format == FormatJSON {
+		// never color in json
+		data = encodeJSON(e)
+	} else {
+		data = encodeText(e, s.Color)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(data)
+	return err
+}
+
+func (s *WriterSink) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if w, ok := s.w.(syncer); ok {
+		return w.Sync()
+	}
+	return nil
+}
+
+func (s *WriterSink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// file with rotate support
+type FileSink struct {
+	*WriterSink
+	*RotateConf
+}
+
+func NewFileSink(filename string, format int) (*FileSink, error) {
+	return newFileSink(filename, format, &RotateConf{})
+}
+
+func newFileSink(filename string, format int, conf *RotateConf) (*FileSink, error) {
+	file, err := newFileWriter(filename, conf)
+	if err != nil {
+		return nil, err
+	}
+	s := FileSink{
+		WriterSink: NewWriterSink(file, format, false),
+		RotateConf: conf,
+	}
+	s.closer = file
+	return &s, nil
+}
+
+// logger only write to sink, not registered and not DefaultLog
+func NewWithSink(sink Sink) *LevelLogger {
+	l := LevelLogger{
+		out:   &fanout{},
+		level: LevelDebug,
+	}
+	l.out.add(0, sink)
+	l.Logger = NewStdLogger(&l, LevelInfo)
+	return &l
+}
+
+// open sink by dest, same as Install
+func OpenSink(dest string) (Sink, error) {
+	if w := stdWriter(dest); w != nil {
+		return NewWriterSink(w, FormatText, useColor(ColorAuto, w)), nil
+	}
+	if strings.Contains(dest, "://") {
+		return openURLSink(dest)
+	}
+	return NewFileSink(dest, FormatText)
+}
+
+func stdWriter(dest string) *os.File {
+	switch dest {
+	case "stdout":
+		return os.Stdout
+	case "stderr":
+		return os.Stderr
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,154 @@
+// bridge between LevelLogger and log/slog
+// slog.SetDefault(slog.New(log.NewSlogHandler(log.GetLogger())))
+// l := log.NewFromSlog(slog.NewJSONHandler(os.Stdout, nil))
+
+package log
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+)
+
+func levelFromSlog(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return LevelDebug
+	case level < slog.LevelWarn:
+		return LevelInfo
+	case level < slog.LevelError:
+		return LevelWarn
+	case level < slog.LevelError+4:
+		return LevelError
+	default:
+		return LevelFatal
+	}
+}
+
+func levelToSlog(level int) slog.Level {
+	switch {
+	case level < LevelInfo:
+		return slog.LevelDebug
+	case level < LevelWarn:
+		return slog.LevelInfo
+	case level < LevelError:
+		return slog.LevelWarn
+	case level < LevelFatal:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
+
+// slog.Handler write to LevelLogger sinks, use LevelLogger level
+type SlogHandler struct {
+	l      *LevelLogger
+	fields Fields
+	group  string
+}
+
+func NewSlogHandler(l *LevelLogger) *SlogHandler {
+	return &SlogHandler{l: l}
+}
+
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return levelFromSlog(level) >= h.l.GetLevel()
+}
+
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	e := Entry{
+		Time:    r.Time,
+		Level:   levelFromSlog(r.Level),
+		PC:      r.PC,
+		Prefix:  h.l.Prefix,
+		Message: r.Message,
+	}
+	if e.Time.IsZero() {
+		e.Time = now()
+	}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.File, e.Line = frame.File, frame.Line
+	}
+
+	e.Fields = append(e.Fields, ContextFields(ctx)...)
+	e.Fields = append(e.Fields, h.fields...)
+	r.Attrs(func(a slog.Attr) bool {
+		e.Fields = appendAttr(e.Fields, h.group, a)
+		return true
+	})
+
+	h.l.out.write(&e)
+	return nil
+}
+
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	n := *h
+	n.fields = append(Fields{}, h.fields...)
+	for _, a := range attrs {
+		n.fields = appendAttr(n.fields, h.group, a)
+	}
+	return &n
+}
+
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	n := *h
+	n.group = h.group + name + "."
+	return &n
+}
+
+// group is flatten as group.key
+func appendAttr(fields Fields, group string, a slog.Attr) Fields {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			group = group + a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, group, ga)
+		}
+		return fields
+	}
+	return append(fields, Field{Key: group + a.Key, Value: a.Value.Any()})
+}
+
+// Sink write to slog.Handler
+type slogSink struct {
+	h slog.Handler
+}
+
+func (s slogSink) Write(e *Entry) error {
+	level := levelToSlog(e.Level)
+	ctx := context.Background()
+	if !s.h.Enabled(ctx, level) {
+		return nil
+	}
+	r := slog.NewRecord(e.Time, level, e.Prefix+e.Message, e.PC)
+	for _, f := range e.Fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	return s.h.Handle(ctx, r)
+}
+
+func (s slogSink) Close() error {
+	return nil
+}
+
+// LevelLogger write to slog.Handler, level is checked by both
+func NewFromSlog(h slog.Handler) *LevelLogger {
+	l := LevelLogger{
+		out:   &fanout{},
+		level: LevelDebug,
+	}
+	l.out.add(0, slogSink{h: h})
+	l.Logger = slog.NewLogLogger(h, slog.LevelInfo)
+	return &l
+}
+
+var _ slog.Handler = (*SlogHandler)(nil)
@@ -0,0 +1,106 @@
+// redirect stdlib log and panic into LevelLogger
+// log.Install("app.log", log.WithStdlog(log.LevelInfo))
+// server.ErrorLog = log.NewStdLogger(log.GetLogger(), log.LevelWarn)
+// defer log.Recover(false)
+
+package log
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// io.Writer for stdlib log.Logger, one Write is one line
+type stdlogWriter struct {
+	l     *LevelLogger
+	level int
+}
+
+func (w stdlogWriter) Write(p []byte) (int, error) {
+	if w.level < w.l.GetLevel() {
+		return len(p), nil
+	}
+
+	e := Entry{
+		Time:    now(),
+		Level:   w.level,
+		Prefix:  w.l.Prefix,
+		Message: strings.TrimSuffix(string(p), "\n"),
+	}
+	// stdlib log with Lshortfile: file.go:12: message
+	if idx := strings.Index(e.Message, ": "); idx > 0 {
+		if colon := strings.LastIndexByte(e.Message[:idx], ':'); colon > 0 {
+			if line, err := strconv.Atoi(e.Message[colon+1 : idx]); err == nil {
+				e.File, e.Line = e.Message[:colon], line
+				e.Message = e.Message[idx+2:]
+			}
+		}
+	}
+	w.l.out.write(&e)
+	return len(p), nil
+}
+
+// stdlib log.Logger write to l at level, for http.Server.ErrorLog and others
+func NewStdLogger(l *LevelLogger, level int) *log.Logger {
+	return log.New(stdlogWriter{l: l, level: level}, "", log.Lshortfile)
+}
+
+// redirect stdlib default logger to l at level
+func RedirectStdlog(l *LevelLogger, level int) {
+	log.SetFlags(log.Lshortfile)
+	log.SetPrefix("")
+	log.SetOutput(stdlogWriter{l: l, level: level})
+}
+
+func WithStdlog(level int) Option {
+	return func(l *LevelLogger) {
+		l.stdlog = level
+	}
+}
+
+// use as defer log.Recover(false) in goroutine
+// panic is logged with stack at Error, repanic after log if repanic is true
+func Recover(repanic bool) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	l := Get("")
+	// caller is the function panic
+	l.output(LevelError, 3+panicDepth(), l.Prefix, fmt.Sprintf("panic: %v", r), Fields{
+		{Key: "stack", Value: string(debug.Stack())},
+	})
+	if repanic {
+		Sync()
+		panic(r)
+	}
+}
+
+// frames between Recover and the function panic, like gopanic or sigpanic
+func panicDepth() int {
+	pcs := make([]uintptr, 32)
+	// skip Callers, panicDepth and Recover
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	depth := 0
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") || !more {
+			return depth
+		}
+		depth++
+	}
+}
+
+// run f in goroutine with Recover
+func Go(f func(), repanic bool) {
+	go func() {
+		defer Recover(repanic)
+		f()
+	}()
+}
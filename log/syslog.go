@@ -0,0 +1,214 @@
+// syslog and journald sink
+// log.Install("syslog://localhost:514")
+// log.Install("unixgram:///dev/log")
+// log.Install("journald://")  default /run/systemd/journal/socket
+// app name is LevelLogger.Prefix, or program name if empty
+
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// facility user
+const syslogFacility = 1 << 3
+
+// ref: https://tools.ietf.org/html/rfc5424#section-6.2.1
+func syslogSeverity(level int) int {
+	switch {
+	case level < LevelInfo:
+		return 7
+	case level < LevelWarn:
+		return 6
+	case level < LevelError:
+		return 4
+	case level < LevelFatal:
+		return 3
+	default:
+		return 2
+	}
+}
+
+func appName(prefix string) string {
+	name := strings.TrimRight(prefix, ": |")
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+	return name
+}
+
+// datagram sink, reconnect once if write fail
+type packetSink struct {
+	mu      sync.Mutex
+	network string
+	addr    string
+	conn    net.Conn
+	encode  func(e *Entry) []byte
+}
+
+func (s *packetSink) Write(e *Entry) error {
+	data := s.encode(e)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			s.conn, err = net.Dial(s.network, s.addr)
+			if err != nil {
+				return err
+			}
+		}
+		if _, err = s.conn.Write(data); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *packetSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// udp to remote syslog
+func NewSyslogSink(addr string) Sink {
+	hostname, _ := os.Hostname()
+	return &packetSink{
+		network: "udp",
+		addr:    addr,
+		encode: func(e *Entry) []byte {
+			return encodeSyslog(e, hostname)
+		},
+	}
+}
+
+// unix datagram to local syslog, like /dev/log
+func NewUnixSyslogSink(path string) Sink {
+	return &packetSink{
+		network: "unixgram",
+		addr:    path,
+		encode: func(e *Entry) []byte {
+			return encodeSyslog(e, "")
+		},
+	}
+}
+
+// journald native protocol
+// ref: https://systemd.io/JOURNAL_NATIVE_PROTOCOL/
+func NewJournaldSink(path string) Sink {
+	if path == "" {
+		path = journalSocket
+	}
+	return &packetSink{
+		network: "unixgram",
+		addr:    path,
+		encode:  encodeJournald,
+	}
+}
+
+// same as stdlib log/syslog, hostname is empty for local
+func encodeSyslog(e *Entry, hostname string) []byte {
+	pri := syslogFacility | syslogSeverity(e.Level)
+	message := e.Message
+	if len(e.Fields) > 0 {
+		message = formatFields(e.Message, e.Fields)
+	}
+	message = strings.TrimSuffix(message, "\n")
+
+	if hostname == "" {
+		return []byte(fmt.Sprintf("<%d>%s %s[%d]: %s\n",
+			pri, e.Time.Format("Jan _2 15:04:05"), appName(e.Prefix), os.Getpid(), message,
+		))
+	}
+	return []byte(fmt.Sprintf("<%d>%s %s %s[%d]: %s\n",
+		pri, e.Time.Format("2006-01-02T15:04:05.000000Z07:00"), hostname, appName(e.Prefix), os.Getpid(), message,
+	))
+}
+
+func encodeJournald(e *Entry) []byte {
+	b := bytes.Buffer{}
+	writeJournaldField(&b, "MESSAGE", e.Message)
+	writeJournaldField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(e.Level)))
+	writeJournaldField(&b, "SYSLOG_IDENTIFIER", appName(e.Prefix))
+	if e.File != "" {
+		writeJournaldField(&b, "CODE_FILE", e.File)
+		writeJournaldField(&b, "CODE_LINE", strconv.Itoa(e.Line))
+	}
+	for _, f := range e.Fields {
+		writeJournaldField(&b, journaldKey(f.Key), formatValue(f.Value))
+	}
+	return b.Bytes()
+}
+
+// value with newline use binary format
+func writeJournaldField(b *bytes.Buffer, key string, value string) {
+	b.WriteString(key)
+	if !strings.ContainsRune(value, '\n') {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// only A-Z 0-9 _ and not start with _ or digit
+func journaldKey(key string) string {
+	k := []byte(strings.ToUpper(key))
+	for i, c := range k {
+		if !(c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			k[i] = '_'
+		}
+	}
+	s := strings.TrimLeft(string(k), "_0123456789")
+	if s == "" {
+		return "FIELD"
+	}
+	return s
+}
+
+// syslog://host:port, unixgram:///dev/log, journald://[path]
+func openURLSink(dest string) (Sink, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "syslog":
+		host := u.Host
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "514")
+		}
+		return NewSyslogSink(host), nil
+	case "unixgram":
+		return NewUnixSyslogSink(u.Path), nil
+	case "journald":
+		return NewJournaldSink(u.Path), nil
+	default:
+		return nil, fmt.Errorf("unknown log dest [%s]", dest)
+	}
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+// +build !windows
+
+package log
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	log := Install("syslog://"+conn.LocalAddr().String(), WithName("syslog"))
+	log.SetPrefix("myapp:")
+	log.Warnw("syslog", "k", "v")
+	log.Printf("printf")
+	log.Close()
+
+	s := readPacket(t, conn)
+	if !strings.HasPrefix(s, "<12>") || !strings.Contains(s, " myapp[") || !strings.HasSuffix(s, "]: syslog|k=v\n") {
+		t.Errorf("wrong syslog: %q", s)
+	}
+	if s = readPacket(t, conn); !strings.HasPrefix(s, "<14>") || !strings.HasSuffix(s, "]: printf\n") {
+		t.Errorf("wrong syslog printf: %q", s)
+	}
+}
+
+func TestJournald(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "zgo_log")
+	defer os.RemoveAll(dir)
+
+	for _, scheme := range []string{"unixgram", "journald"} {
+		path := filepath.Join(dir, scheme+".sock")
+		conn, err := net.ListenPacket("unixgram", path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		log := Install(scheme+"://"+path, WithName(scheme))
+		log.Errorw("multi\nline", "request-id", "abc")
+		log.Close()
+
+		s := readPacket(t, conn)
+		conn.Close()
+		if scheme == "unixgram" {
+			if !strings.HasPrefix(s, "<11>") || !strings.HasSuffix(s, "]: multi\nline|request-id=abc\n") {
+				t.Errorf("wrong unix syslog: %q", s)
+			}
+			continue
+		}
+		for _, expect := range []string{"MESSAGE\n\x0a\x00\x00\x00\x00\x00\x00\x00multi\nline\n", "PRIORITY=3\n", "CODE_FILE=", "REQUEST_ID=abc\n"} {
+			if !strings.Contains(s, expect) {
+				t.Errorf("%q not in journald: %q", expect, s)
+			}
+		}
+	}
+}
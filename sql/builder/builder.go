@@ -1,9 +1,6 @@
 // build sql just like use zpy/base/dbpool.py
 // not build for all sql
-
-// TODO use $1 instead of ? for pg
-// TODO SelectJoin
-// TODO InsertMany
+// package func use MySQL dialect, use New(dialect) for others
 
 package builder
 
@@ -16,174 +13,219 @@ import (
 type Where map[string]interface{}
 type Values map[string]interface{}
 
-func Select(table string, where Where) (string, []interface{}) {
-
-	var args []interface{}
+type Builder struct {
+	dialect Dialect
+}
 
-	field := "*"
-	groupby := ""
-	having := Where{}
-	other := ""
+var Default = New(MySQL)
 
-	if value, ok := where["_field"]; ok {
-		field = value.(string)
-		delete(where, "_field")
-	}
-	if value, ok := where["_groupby"]; ok {
-		groupby = value.(string)
-		delete(where, "_groupby")
-	}
-	if value, ok := where["_having"]; ok {
-		having = value.(Where)
-		delete(where, "_having")
-	}
-	if value, ok := where["_other"]; ok {
-		other = value.(string)
-		delete(where, "_other")
-	}
+func New(dialect Dialect) *Builder {
+	return &Builder{dialect: dialect}
+}
 
-	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf("SELECT %s FROM `%s`", field, table))
+func (b *Builder) Dialect() Dialect {
+	return b.dialect
+}
 
-	// where
-	if len(where) > 0 {
-		sql, arg := where2sql(where)
-		sb.WriteString(" WHERE ")
-		sb.WriteString(sql)
-		args = append(args, arg...)
-	}
+// sql with args, placeholder numbered by dialect
+type stmt struct {
+	strings.Builder
+	dialect Dialect
+	args    []interface{}
+}
 
-	// groupby
-	if groupby != "" {
-		sb.WriteString(" GROUP BY ")
-		sb.WriteString(groupby)
-	}
+func (b *Builder) stmt() *stmt {
+	return &stmt{dialect: b.dialect}
+}
 
-	// having
-	if len(having) > 0 {
-		sql, arg := where2sql(having)
-		sb.WriteString(" HAVING ")
-		sb.WriteString(sql)
-		args = append(args, arg...)
+// each part of table.name is quoted, * is not
+func (s *stmt) quote(ident string) {
+	for i, part := range strings.Split(ident, ".") {
+		if i > 0 {
+			s.WriteString(".")
+		}
+		if part == "*" {
+			s.WriteString(part)
+		} else {
+			s.WriteString(s.dialect.Quote(part))
+		}
 	}
+}
 
-	// orderby limit offset
-	if other != "" {
-		sb.WriteString(" ")
-		sb.WriteString(other)
-	}
+func (s *stmt) arg(value interface{}) {
+	s.args = append(s.args, value)
+	s.WriteString(s.dialect.Placeholder(len(s.args)))
+}
 
-	return sb.String(), args
+func Select(table string, where Where) (string, []interface{}) {
+	return Default.Select(table, where)
 }
 
 func Insert(table string, value Values) (string, []interface{}) {
-	k, v, i := values2insert(value)
-	sql := fmt.Sprintf("INSERT INTO `%s`(%s) VALUES(%s)", table, k, v)
-	return sql, i
+	return Default.Insert(table, value)
+}
+
+func InsertMany(table string, values []Values) (string, []interface{}, error) {
+	return Default.InsertMany(table, values)
 }
 
+// mysql accept _limit, only _offset is invalid and sql is empty for it
+// use Builder.Update to get the error
 func Update(table string, value Values, where Where) (string, []interface{}) {
-	var args []interface{}
+	sql, args, _ := Default.Update(table, value, where)
+	return sql, args
+}
 
-	other := ""
-	if value, ok := where["_other"]; ok {
-		other = value.(string)
-		delete(where, "_other")
-	}
+// same as Update for _limit and _offset
+func Delete(table string, where Where) (string, []interface{}) {
+	sql, args, _ := Default.Delete(table, where)
+	return sql, args
+}
 
-	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf("UPDATE `%s`", table))
+func FormatSql(query string, args ...interface{}) string {
+	return Default.FormatSql(query, args...)
+}
 
-	// set
-	k, v := values2set(value)
-	sb.WriteString(" SET ")
-	sb.WriteString(k)
-	args = append(args, v...)
+// magic keys _field _groupby _having _other _limit _offset, see Query
+func (b *Builder) Select(table string, where Where) (string, []interface{}) {
+	return b.fromWhere(table, where).Sql()
+}
 
-	// where
-	if len(where) > 0 {
-		sql, arg := where2sql(where)
-		sb.WriteString(" WHERE ")
-		sb.WriteString(sql)
-		args = append(args, arg...)
-	}
+func (b *Builder) Insert(table string, value Values) (string, []interface{}) {
+	s := b.stmt()
+	s.WriteString("INSERT INTO ")
+	s.quote(table)
+	s.insert(value)
+	return s.String(), s.args
+}
 
-	// orderby limit offset
-	if other != "" {
-		sb.WriteString(" ")
-		sb.WriteString(other)
+// all rows must have same columns
+func (b *Builder) InsertMany(table string, values []Values) (string, []interface{}, error) {
+	cols, err := Columns(values)
+	if err != nil {
+		return "", nil, err
 	}
 
-	return sb.String(), args
+	s := b.stmt()
+	s.WriteString("INSERT INTO ")
+	s.quote(table)
+	s.WriteString("(")
+	for i, k := range cols {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.quote(k)
+	}
+	s.WriteString(") VALUES")
+	for i, value := range values {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.WriteString("(")
+		for j, k := range cols {
+			if j > 0 {
+				s.WriteString(",")
+			}
+			s.arg(value[k])
+		}
+		s.WriteString(")")
+	}
+	return s.String(), s.args, nil
 }
 
-func Delete(table string, where Where) (string, []interface{}) {
-	var args []interface{}
-
-	other := ""
-	if value, ok := where["_other"]; ok {
-		other = value.(string)
-		delete(where, "_other")
+// sorted columns of rows, error if rows have different columns
+func Columns(values []Values) ([]string, error) {
+	if len(values) == 0 || len(values[0]) == 0 {
+		return nil, fmt.Errorf("no values to insert")
 	}
+	cols := sortedKeys(values[0])
 
-	sb := strings.Builder{}
-	sb.WriteString(fmt.Sprintf("DELETE FROM `%s`", table))
-
-	// where
-	if len(where) > 0 {
-		sql, arg := where2sql(where)
-		sb.WriteString(" WHERE ")
-		sb.WriteString(sql)
-		args = append(args, arg...)
+	for i, value := range values[1:] {
+		if len(value) != len(cols) {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", i+1, len(value), len(cols))
+		}
+		for _, k := range cols {
+			if _, ok := value[k]; !ok {
+				return nil, fmt.Errorf("row %d has no column [%s]", i+1, k)
+			}
+		}
 	}
+	return cols, nil
+}
 
-	// orderby limit offset
-	if other != "" {
-		sb.WriteString(" ")
-		sb.WriteString(other)
-	}
+// _limit is only supported by mysql
+func (b *Builder) Update(table string, value Values, where Where) (string, []interface{}, error) {
+	return b.fromWhere(table, where).Update(value)
+}
 
-	return sb.String(), args
+// _limit is only supported by mysql
+func (b *Builder) Delete(table string, where Where) (string, []interface{}, error) {
+	return b.fromWhere(table, where).Delete()
 }
 
-func FormatSql(query string, args ...interface{}) string {
+// XXX for logging only, not real sql
+func (b *Builder) FormatSql(query string, args ...interface{}) string {
 	if len(args) == 0 {
 		return query
 	}
-	if strings.Count(query, "?") != len(args) {
-		return query
+	if b.dialect.Placeholder(1) == b.dialect.Placeholder(2) {
+		// same placeholder for all args, like ?
+		p := b.dialect.Placeholder(1)
+		if strings.Count(query, p) != len(args) {
+			return query
+		}
+		query = strings.Replace(strings.Replace(query, "%", "%%", -1), p, "[%+v]", -1)
+		return fmt.Sprintf(query, args...)
 	}
-	// XXX for logging only, not real sql
-	// TODO pg is not '?'
-	query = strings.Replace(query, "?", "[%+v]", -1)
-	return fmt.Sprintf(query, args...)
+	// numbered placeholder, replace from last so $1 not match $10
+	for i := len(args); i > 0; i-- {
+		query = strings.Replace(query, b.dialect.Placeholder(i), fmt.Sprintf("[%+v]", args[i-1]), -1)
+	}
+	return query
 }
 
-func values2insert(values Values) (string, string, []interface{}) {
-	var args []interface{}
-	var name []string
-	var value []string
-	for k, v := range values {
-		name = append(name, fmt.Sprintf("`%s`", k))
-		value = append(value, "?")
-		args = append(args, v)
+func (s *stmt) insert(values Values) {
+	keys := sortedKeys(values)
+	s.WriteString("(")
+	for i, k := range keys {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.quote(k)
+	}
+	s.WriteString(") VALUES(")
+	for i, k := range keys {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.arg(values[k])
 	}
-	return strings.Join(name, ","), strings.Join(value, ","), args
+	s.WriteString(")")
 }
 
-func values2set(values Values) (sql string, args []interface{}) {
-	var sqls []string
-	for k, v := range values {
-		sqls = append(sqls, fmt.Sprintf("`%s`=?", k))
-		args = append(args, v)
+func (s *stmt) set(values Values) {
+	for i, k := range sortedKeys(values) {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.quote(k)
+		s.WriteString("=")
+		s.arg(values[k])
 	}
-	return strings.Join(sqls, ","), args
 }
 
-func where2sql(where Where) (sql string, args []interface{}) {
+func (s *stmt) where(where Where) {
 	var key, op string
-	var sqls []string
-	for k, v := range where {
+	for i, k := range sortedKeys(Values(where)) {
+		v := where[k]
+		if i > 0 {
+			s.WriteString(" and ")
+		}
+		if strings.HasPrefix(k, "_") && isCond(v) {
+			s.item(v)
+			continue
+		}
+
 		k = strings.Trim(k, " ")
 		idx := strings.IndexByte(k, ' ')
 		if idx == -1 {
@@ -193,40 +235,48 @@ func where2sql(where Where) (sql string, args []interface{}) {
 			key = k[:idx]
 			op = k[idx+1:]
 		}
-		s, i := exp2sql(key, op, v)
-		sqls = append(sqls, s)
-		args = append(args, i...)
+		s.exp(key, op, v)
 	}
-	return strings.Join(sqls, " and "), args
 }
 
-func exp2sql(key string, op string, value interface{}) (sql string, args []interface{}) {
-
-	builder := strings.Builder{}
-	builder.WriteString(fmt.Sprintf("(`%s` %s ", key, op))
-
-	if strings.Contains(op, "in") {
-		builder.WriteString("(")
+func (s *stmt) exp(key string, op string, value interface{}) {
+	s.WriteString("(")
+	s.quote(key)
+	s.WriteString(" ")
+	s.WriteString(op)
+	s.WriteString(" ")
+
+	if e, ok := value.(Expr); ok {
+		s.expr(e)
+		s.WriteString(")")
+	} else if c, ok := value.(Col); ok {
+		s.quote(string(c))
+		s.WriteString(")")
+	} else if q, ok := value.(*Query); ok {
+		s.WriteString("(")
+		q.selectTo(s)
+		s.WriteString("))")
+	} else if strings.Contains(op, "in") {
+		s.WriteString("(")
 		for idx, v := range interface2slice(value) {
-			if idx == 0 {
-				builder.WriteString("?")
-			} else {
-				builder.WriteString(",?")
+			if idx > 0 {
+				s.WriteString(",")
 			}
-			args = append(args, v)
+			s.arg(v)
 		}
-		builder.WriteString("))")
+		s.WriteString("))")
 	} else if strings.Contains(op, "between") {
-		builder.WriteString("? and ?)")
 		v := interface2slice(value)
-		args = append(args, v[0])
-		args = append(args, v[1])
+		s.arg(v[0])
+		s.WriteString(" and ")
+		s.arg(v[1])
+		s.WriteString(")")
 	} else {
-		builder.WriteString("?)")
-		args = append(args, value)
+		s.arg(value)
+		s.WriteString(")")
 	}
-	return builder.String(), args
 }
+
 func interface2slice(value interface{}) []interface{} {
 	v := reflect.ValueOf(value)
 	if v.Kind() != reflect.Slice {
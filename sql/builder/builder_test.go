@@ -74,3 +74,309 @@ func TestAll(t *testing.T) {
 	log.Debug("sql: %s, args: %v", sql, args)
 
 }
+
+func TestDialect(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		sql     string
+	}{
+		{MySQL, "SELECT * FROM `test` WHERE (`id` in (?,?)) HAVING (`n` > ?) LIMIT 10 OFFSET 20"},
+		{Postgres, `SELECT * FROM "test" WHERE ("id" in ($1,$2)) HAVING ("n" > $3) LIMIT 10 OFFSET 20`},
+		{SQLite, `SELECT * FROM "test" WHERE ("id" in (?,?)) HAVING ("n" > ?) LIMIT 10 OFFSET 20`},
+		{SQLServer, "SELECT * FROM [test] WHERE ([id] in (@p1,@p2)) HAVING ([n] > @p3) ORDER BY (SELECT NULL) OFFSET 20 ROWS FETCH NEXT 10 ROWS ONLY"},
+	}
+	for _, c := range cases {
+		sql, args := New(c.dialect).Select("test", Where{
+			"id in":   []int{1, 2},
+			"_having": Where{"n >": 3},
+			"_limit":  10,
+			"_offset": 20,
+		})
+		if sql != c.sql || len(args) != 3 {
+			t.Errorf("%s: %s %v", c.dialect.Name(), sql, args)
+		}
+	}
+
+	// sqlserver OFFSET FETCH need ORDER BY
+	sqlserver := New(SQLServer)
+	if sql, _ := sqlserver.From("t").Limit(10).Sql(); sql != "SELECT * FROM [t] ORDER BY (SELECT NULL) OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY" {
+		t.Errorf("sqlserver limit: %s", sql)
+	}
+	if sql, _ := sqlserver.From("t").OrderBy("id").Limit(10).Sql(); sql != "SELECT * FROM [t] ORDER BY [id] OFFSET 0 ROWS FETCH NEXT 10 ROWS ONLY" {
+		t.Errorf("sqlserver order limit: %s", sql)
+	}
+	if sql, _ := sqlserver.Select("t", Where{"_other": "order by id", "_limit": 1}); sql != "SELECT * FROM [t] order by id OFFSET 0 ROWS FETCH NEXT 1 ROWS ONLY" {
+		t.Errorf("sqlserver other order: %s", sql)
+	}
+	if sql, _ := sqlserver.From("t").Sql(); sql != "SELECT * FROM [t]" {
+		t.Errorf("sqlserver no limit: %s", sql)
+	}
+
+	sql, args, err := New(Postgres).Update("test", Values{"name": "a"}, Where{"id": 1})
+	if err != nil || sql != `UPDATE "test" SET "name"=$1 WHERE ("id" = $2)` || len(args) != 2 {
+		t.Errorf("update: %s %v", sql, args)
+	}
+	if s := New(Postgres).FormatSql(sql, args...); s != `UPDATE "test" SET "name"=[a] WHERE ("id" = [1])` {
+		t.Errorf("format: %s", s)
+	}
+
+	args = make([]interface{}, 11)
+	for i := range args {
+		args[i] = i + 1
+	}
+	if s := New(Postgres).FormatSql("$1 $10 $11", args...); s != "[1] [10] [11]" {
+		t.Errorf("format: %s", s)
+	}
+	if s := FormatSql("name like '%a' and id = ?", 1); s != "name like '%a' and id = [1]" {
+		t.Errorf("format: %s", s)
+	}
+
+	if l := MySQL.Limit(-1, 5); l != "LIMIT 18446744073709551615 OFFSET 5" {
+		t.Errorf("limit: %s", l)
+	}
+	if l := Postgres.Limit(-1, 5); l != "OFFSET 5" {
+		t.Errorf("limit: %s", l)
+	}
+	if q := MySQL.Quote("a`b"); q != "`a``b`" {
+		t.Errorf("quote: %s", q)
+	}
+	if DialectOf("pgx") != Postgres || DialectOf("mysql") != MySQL || DialectOf("sqlite3") != SQLite {
+		t.Errorf("dialect of driver")
+	}
+}
+
+func TestInsertMany(t *testing.T) {
+	sql, args, err := New(Postgres).InsertMany("test", []Values{
+		{"id": 1, "name": "a"},
+		{"name": "b", "id": 2},
+	})
+	if err != nil || sql != `INSERT INTO "test"("id","name") VALUES($1,$2),($3,$4)` {
+		t.Errorf("insert many: %s %v", sql, err)
+	}
+	if fmt.Sprint(args) != "[1 a 2 b]" {
+		t.Errorf("args: %v", args)
+	}
+
+	_, _, err = InsertMany("test", []Values{{"id": 1}, {"name": "b"}})
+	if err == nil {
+		t.Errorf("different columns not checked")
+	}
+	_, _, err = InsertMany("test", []Values{{"id": 1}, {"id": 2, "name": "b"}})
+	if err == nil {
+		t.Errorf("different columns not checked")
+	}
+	_, _, err = InsertMany("test", nil)
+	if err == nil {
+		t.Errorf("empty rows not checked")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	values := Values{"id": 1, "name": "a", "n": 1}
+	update := Values{"name": Inserted, "n": 0}
+
+	sql, args, err := Upsert("test", values, []string{"id"}, update)
+	if err != nil || sql != "INSERT INTO `test`(`id`,`n`,`name`) VALUES(?,?,?) ON DUPLICATE KEY UPDATE `n`=?,`name`=VALUES(`name`)" {
+		t.Errorf("mysql: %s %s", sql, err)
+	}
+	if fmt.Sprint(args) != "[1 1 a 0]" {
+		t.Errorf("args: %v", args)
+	}
+
+	sql, _, err = New(Postgres).Upsert("test", values, []string{"id"}, update)
+	if err != nil || sql != `INSERT INTO "test"("id","n","name") VALUES($1,$2,$3) ON CONFLICT ("id") DO UPDATE SET "n"=$4,"name"=excluded."name"` {
+		t.Errorf("postgres: %s %s", sql, err)
+	}
+
+	sql, _, err = Upsert("test", values, nil, nil)
+	if err != nil || sql != "INSERT IGNORE INTO `test`(`id`,`n`,`name`) VALUES(?,?,?)" {
+		t.Errorf("mysql ignore: %s %s", sql, err)
+	}
+	sql, _, err = New(SQLite).Upsert("test", values, nil, nil)
+	if err != nil || sql != `INSERT INTO "test"("id","n","name") VALUES(?,?,?) ON CONFLICT DO NOTHING` {
+		t.Errorf("sqlite ignore: %s %s", sql, err)
+	}
+
+	if _, _, err = New(SQLite).Upsert("test", values, nil, update); err == nil {
+		t.Errorf("conflict keys not checked")
+	}
+	if _, _, err = New(SQLServer).Upsert("test", values, nil, nil); err == nil {
+		t.Errorf("sqlserver not supported")
+	}
+}
+
+func TestCond(t *testing.T) {
+	sql, args := Select("test", Where{
+		"_or": Or{Where{"b": 2}, And{Where{"c": 3}, Not{Where{"d": 4}}}},
+	})
+	if sql != "SELECT * FROM `test` WHERE ((`b` = ?) or ((`c` = ?) and (not (`d` = ?))))" || fmt.Sprint(args) != "[2 3 4]" {
+		t.Errorf("select: %s %v", sql, args)
+	}
+
+	sql, args = New(Postgres).Select("test", Where{
+		"_raw":    Raw("e = ? or e is null", 5),
+		"_field":  "name",
+		"_having": Where{"_or": Or{Where{"n >": 1}, Not{}}},
+	})
+	if sql != `SELECT "name" FROM "test" WHERE (e = $1 or e is null) HAVING (("n" > $2) or (not (1=1)))` || fmt.Sprint(args) != "[5 1]" {
+		t.Errorf("having: %s %v", sql, args)
+	}
+
+	sql, args, _ = New(Postgres).Update("test", Values{"name": "a"}, Where{
+		"t <":  Raw("now() - interval ? day", 1),
+		"_not": Not{Where{"id in": []int{1, 2}}},
+	})
+	log.Debug("sql: %s %v", sql, args)
+	if len(args) != 4 || args[0] != "a" {
+		t.Errorf("update: %s %v", sql, args)
+	}
+
+	sql, args = Delete("test", Where{"_": Or{Where{"a": 1}, Raw("b is null")}})
+	if sql != "DELETE FROM `test` WHERE ((`a` = ?) or (b is null))" || len(args) != 1 {
+		t.Errorf("delete: %s %v", sql, args)
+	}
+
+	// column start with _ is not Cond, nil Cond is empty
+	sql, args = Select("test", Where{"_id": 1, "_x": nil, "_and": And{nil}})
+	if sql != "SELECT * FROM `test` WHERE ((1=1)) and (`_id` = ?) and (`_x` = ?)" || fmt.Sprint(args) != "[1 <nil>]" {
+		t.Errorf("underscore column: %s %v", sql, args)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	where := Where{"name": "a", "id >": 1, "_or": Or{Where{"n": 1}, Where{"m": 2}}}
+	q := From("test").Columns("id", "count(*) as n").Where(where)
+	q1 := q.OrderBy("id desc").Limit(10).Offset(20)
+	q2 := q.GroupBy("name").Having(Where{"n >": 1}).Where(Raw("x is null"))
+
+	sql, args := q.Sql()
+	if sql != "SELECT `id`,count(*) as n FROM `test` WHERE ((`n` = ?) or (`m` = ?)) and (`id` > ?) and (`name` = ?)" || fmt.Sprint(args) != "[1 2 1 a]" {
+		t.Errorf("query: %s %v", sql, args)
+	}
+	for i := 0; i < 10; i++ {
+		if s, _ := q.Sql(); s != sql {
+			t.Errorf("not deterministic: %s", s)
+		}
+	}
+
+	sql, _ = q1.Sql()
+	if sql != "SELECT `id`,count(*) as n FROM `test` WHERE ((`n` = ?) or (`m` = ?)) and (`id` > ?) and (`name` = ?) ORDER BY `id` desc LIMIT 10 OFFSET 20" {
+		t.Errorf("query: %s", sql)
+	}
+	sql, args = q2.Sql()
+	if sql != "SELECT `id`,count(*) as n FROM `test` WHERE ((`n` = ?) or (`m` = ?)) and (`id` > ?) and (`name` = ?) and (x is null) GROUP BY `name` HAVING (`n` > ?)" || len(args) != 5 {
+		t.Errorf("query: %s %v", sql, args)
+	}
+
+	sql, args, _ = From("test").Where(Where{"id": 1}).Update(Values{"b": 2, "a": 1})
+	if sql != "UPDATE `test` SET `a`=?,`b`=? WHERE (`id` = ?)" || fmt.Sprint(args) != "[1 2 1]" {
+		t.Errorf("update: %s %v", sql, args)
+	}
+	sql, _, _ = From("test").Where(Where{"id": 1}).Delete()
+	if sql != "DELETE FROM `test` WHERE (`id` = ?)" {
+		t.Errorf("delete: %s", sql)
+	}
+
+	// limit of update and delete only for mysql
+	sql, _, err := From("test").Where(Where{"id >": 1}).OrderBy("id").Limit(2).Delete()
+	if err != nil || sql != "DELETE FROM `test` WHERE (`id` > ?) ORDER BY `id` LIMIT 2" {
+		t.Errorf("delete limit: %s %v", sql, err)
+	}
+	if _, _, err = From("test").Limit(2).Offset(2).Delete(); err == nil {
+		t.Errorf("mysql delete offset not checked")
+	}
+	if sql, _ = Delete("test", Where{"_limit": 1}); sql != "DELETE FROM `test` LIMIT 1" {
+		t.Errorf("delete limit: %s", sql)
+	}
+	if sql, _ = Update("test", Values{"a": 1}, Where{"_limit": 1, "_offset": 1}); sql != "" {
+		t.Errorf("update offset: %s", sql)
+	}
+	if _, _, err = New(Postgres).Update("test", Values{"a": 1}, Where{"_limit": 1}); err == nil {
+		t.Errorf("postgres update limit not checked")
+	}
+	if _, _, err = New(SQLServer).From("test").Limit(1).Delete(); err == nil {
+		t.Errorf("sqlserver delete limit not checked")
+	}
+
+	// empty Where write no WHERE HAVING ON
+	if sql, _ = From("t").Where(Where{}).Sql(); sql != "SELECT * FROM `t`" {
+		t.Errorf("empty where: %s", sql)
+	}
+	if sql, args = From("t").Where(nil, Where{"id": 1}, map[string]interface{}{}).GroupBy("id").Having(Where{}).Sql(); sql != "SELECT * FROM `t` WHERE (`id` = ?) GROUP BY `id`" || len(args) != 1 {
+		t.Errorf("empty where: %s %v", sql, args)
+	}
+	if sql, _ = From("t").LeftJoin("u", Where{}).Sql(); sql != "SELECT * FROM `t` LEFT JOIN `u`" {
+		t.Errorf("empty on: %s", sql)
+	}
+	if sql, _, _ = From("t").Where(Where{}).Update(Values{"a": 1}); sql != "UPDATE `t` SET `a`=?" {
+		t.Errorf("empty where: %s", sql)
+	}
+	if sql, _, _ = From("t").Where(nil).Delete(); sql != "DELETE FROM `t`" {
+		t.Errorf("empty where: %s", sql)
+	}
+	if sql, _ = From("t").Where(And{}).Sql(); sql != "SELECT * FROM `t` WHERE (1=1)" {
+		t.Errorf("empty and: %s", sql)
+	}
+
+	// magic keys are kept
+	where = Where{"id": 1, "_field": "id", "_other": "order by id", "_limit": 1}
+	Select("test", where)
+	Update("test", Values{"a": 1}, where)
+	Delete("test", where)
+	if len(where) != 4 {
+		t.Errorf("where modified: %v", where)
+	}
+}
+
+func TestJoin(t *testing.T) {
+	sql, args := SelectJoin("user u", []Join{
+		LeftJoin("orders as o", Where{"o.uid": Col("u.id"), "o.status": 1}),
+	}, Where{"u.id >": 1, "_field": "u.name, o.amount"})
+	if sql != "SELECT `u`.`name`,`o`.`amount` FROM `user` AS `u` LEFT JOIN `orders` AS `o` ON (`o`.`status` = ?) and (`o`.`uid` = `u`.`id`) WHERE (`u`.`id` > ?)" || fmt.Sprint(args) != "[1 1]" {
+		t.Errorf("select join: %s %v", sql, args)
+	}
+
+	sql, _ = New(Postgres).From("user u").Columns("u.*", "count(o.id) as n").
+		Join("orders o", Where{"o.uid": Col("u.id")}).
+		RightJoin("shop s", Where{"s.id": Col("o.sid")}).
+		GroupBy("u.id").Sql()
+	if sql != `SELECT "u".*,count(o.id) as n FROM "user" AS "u" INNER JOIN "orders" AS "o" ON ("o"."uid" = "u"."id") RIGHT JOIN "shop" AS "s" ON ("s"."id" = "o"."sid") GROUP BY "u"."id"` {
+		t.Errorf("join: %s", sql)
+	}
+
+	if sql, _ = Select("test", Where{"_field": "count(a, b)"}); sql != "SELECT count(a, b) FROM `test`" {
+		t.Errorf("field: %s", sql)
+	}
+	if sql, _ = Select("test", Where{"_field": "DISTINCT name"}); sql != "SELECT DISTINCT name FROM `test`" {
+		t.Errorf("field: %s", sql)
+	}
+	if sql, _ = Select("test", Where{"_field": "id, name as n", "_groupby": "id, name"}); sql != "SELECT `id`,`name` as n FROM `test` GROUP BY `id`,`name`" {
+		t.Errorf("field: %s", sql)
+	}
+	if sql, _ = Select("test", Where{"_groupby": "id, name with rollup"}); sql != "SELECT * FROM `test` GROUP BY id, name with rollup" {
+		t.Errorf("groupby: %s", sql)
+	}
+}
+
+func TestSubquery(t *testing.T) {
+	orders := From("orders").Columns("uid").Where(Where{"amount >": 100})
+
+	sql, args := New(Postgres).Select("user", Where{
+		"name":   "a",
+		"id in":  orders,
+		"_exist": NotExists(From("ban b").Where(Where{"b.uid": Col("user.id"), "b.type": 2})),
+	})
+	if sql != `SELECT * FROM "user" WHERE (NOT EXISTS (SELECT * FROM "ban" AS "b" WHERE ("b"."type" = $1) and ("b"."uid" = "user"."id"))) and ("id" in (SELECT "uid" FROM "orders" WHERE ("amount" > $2))) and ("name" = $3)` || fmt.Sprint(args) != "[2 100 a]" {
+		t.Errorf("subquery: %s %v", sql, args)
+	}
+
+	sql, args, _ = From("user").Where(Where{"id": 1}, Exists(orders)).Delete()
+	if sql != "DELETE FROM `user` WHERE (`id` = ?) and (EXISTS (SELECT `uid` FROM `orders` WHERE (`amount` > ?)))" || fmt.Sprint(args) != "[1 100]" {
+		t.Errorf("exists: %s %v", sql, args)
+	}
+
+	sql, args = New(Postgres).InsertSelect("backup", []string{"uid"}, orders.Limit(10))
+	if sql != `INSERT INTO "backup"("uid") SELECT "uid" FROM "orders" WHERE ("amount" > $1) LIMIT 10` || fmt.Sprint(args) != "[100]" {
+		t.Errorf("insert select: %s %v", sql, args)
+	}
+}
@@ -0,0 +1,158 @@
+// composable condition, nest in Where with unique key start with _
+// key start with _ is still a column if value is not Cond or map, like "_id": 1
+// Where{
+//     "a": 1,
+//     "_or": Or{Where{"b": 2}, And{Where{"c": 3}, Not{Where{"d": 4}}}},
+//     "_raw": Raw("e = ? or e is null", 5),
+// }
+// (`a` = ?) and ((`b` = ?) or ((`c` = ?) and (not (`d` = ?)))) and (e = ? or e is null)
+// Raw is also allowed as value, "t <": Raw("now() - interval ? day", 1)
+
+package builder
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// condition written as a whole, key of it in Where is only a name
+type Cond interface {
+	cond(s *stmt)
+}
+
+// items are Where or Cond
+type And []interface{}
+type Or []interface{}
+
+// not (items joined by and)
+type Not []interface{}
+
+// raw sql with ? as placeholder
+type Expr struct {
+	Sql  string
+	Args []interface{}
+}
+
+func Raw(sql string, args ...interface{}) Expr {
+	return Expr{Sql: sql, Args: args}
+}
+
+func (c And) cond(s *stmt) {
+	if len(c) == 0 {
+		s.WriteString("(1=1)")
+		return
+	}
+	s.join([]interface{}(c), " and ")
+}
+
+func (c Or) cond(s *stmt) {
+	if len(c) == 0 {
+		s.WriteString("(1=0)")
+		return
+	}
+	s.join([]interface{}(c), " or ")
+}
+
+func (c Not) cond(s *stmt) {
+	s.WriteString("(not ")
+	if len(c) == 1 {
+		s.item(c[0])
+	} else {
+		And(c).cond(s)
+	}
+	s.WriteString(")")
+}
+
+func (c Expr) cond(s *stmt) {
+	s.WriteString("(")
+	s.expr(c)
+	s.WriteString(")")
+}
+
+func (s *stmt) join(items []interface{}, sep string) {
+	s.WriteString("(")
+	for i, item := range items {
+		if i > 0 {
+			s.WriteString(sep)
+		}
+		s.item(item)
+	}
+	s.WriteString(")")
+}
+
+// Where with more than one key is wrapped by ()
+func (s *stmt) item(item interface{}) {
+	if c, ok := item.(Cond); ok {
+		c.cond(s)
+		return
+	}
+	where := toWhere(item)
+	switch len(where) {
+	case 0:
+		s.WriteString("(1=1)")
+	case 1:
+		s.where(where)
+	default:
+		s.WriteString("(")
+		s.where(where)
+		s.WriteString(")")
+	}
+}
+
+// rewrite ? to placeholder of dialect
+func (s *stmt) expr(e Expr) {
+	sql := e.Sql
+	for _, arg := range e.Args {
+		idx := strings.IndexByte(sql, '?')
+		if idx == -1 {
+			break
+		}
+		s.WriteString(sql[:idx])
+		s.arg(arg)
+		sql = sql[idx+1:]
+	}
+	s.WriteString(sql)
+}
+
+// Cond or map under key start with _, others like "_id": 1 are column
+func isCond(item interface{}) bool {
+	if _, ok := item.(Cond); ok {
+		return true
+	}
+	return item != nil && reflect.TypeOf(item).Kind() == reflect.Map
+}
+
+// items without nil and empty Where, which write nothing in WHERE HAVING ON
+func nonEmpty(items []interface{}) []interface{} {
+	var r []interface{}
+	for _, item := range items {
+		if item == nil {
+			continue
+		}
+		if _, ok := item.(Cond); !ok {
+			if v := reflect.ValueOf(item); v.Kind() == reflect.Map && v.Len() == 0 {
+				continue
+			}
+		}
+		r = append(r, item)
+	}
+	return r
+}
+
+// Where or other map type like sql.Where, nil is empty Where
+func toWhere(item interface{}) Where {
+	switch v := item.(type) {
+	case nil:
+		return Where{}
+	case Where:
+		return v
+	case map[string]interface{}:
+		return Where(v)
+	}
+	v := reflect.ValueOf(item)
+	if v.Kind() == reflect.Map && v.Type().ConvertibleTo(reflect.TypeOf(Where{})) {
+		return v.Convert(reflect.TypeOf(Where{})).Interface().(Where)
+	}
+	panic(fmt.Sprintf("builder: unknown condition type %T", item))
+}
@@ -0,0 +1,173 @@
+// sql dialect, placeholder, identifier quote and limit offset
+// b := builder.New(builder.Postgres)
+// b.Select("test", builder.Where{"id": 1, "_limit": 10})
+// SELECT * FROM "test" WHERE ("id" = $1) LIMIT 10
+
+package builder
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Dialect interface {
+	Name() string
+	// n start from 1
+	Placeholder(n int) string
+	Quote(ident string) string
+	// limit < 0 means no limit, offset <= 0 means no offset
+	Limit(limit int, offset int) string
+	// max placeholders in one statement
+	MaxArgs() int
+}
+
+var (
+	MySQL     Dialect = mysqlDialect{}
+	Postgres  Dialect = postgresDialect{}
+	SQLite    Dialect = sqliteDialect{}
+	SQLServer Dialect = sqlserverDialect{}
+)
+
+// dialect by database/sql driver name, MySQL if unknown
+func DialectOf(driver string) Dialect {
+	switch driver {
+	case "postgres", "pgx", "pq", "cloudsqlpostgres":
+		return Postgres
+	case "sqlite3", "sqlite":
+		return SQLite
+	case "sqlserver", "mssql":
+		return SQLServer
+	default:
+		return MySQL
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (mysqlDialect) Quote(ident string) string {
+	return quote(ident, "`", "`")
+}
+
+func (mysqlDialect) MaxArgs() int {
+	return 65535
+}
+
+func (mysqlDialect) Limit(limit int, offset int) string {
+	if offset > 0 {
+		if limit < 0 {
+			// max of bigint unsigned, ref: https://dev.mysql.com/doc/refman/8.0/en/select.html
+			return "LIMIT 18446744073709551615 OFFSET " + strconv.Itoa(offset)
+		}
+		return "LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+	}
+	if limit >= 0 {
+		return "LIMIT " + strconv.Itoa(limit)
+	}
+	return ""
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (postgresDialect) Quote(ident string) string {
+	return quote(ident, `"`, `"`)
+}
+
+func (postgresDialect) MaxArgs() int {
+	return 65535
+}
+
+func (postgresDialect) Limit(limit int, offset int) string {
+	var s []string
+	if limit >= 0 {
+		s = append(s, "LIMIT "+strconv.Itoa(limit))
+	}
+	if offset > 0 {
+		s = append(s, "OFFSET "+strconv.Itoa(offset))
+	}
+	return strings.Join(s, " ")
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite3"
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (sqliteDialect) Quote(ident string) string {
+	return quote(ident, `"`, `"`)
+}
+
+// SQLITE_MAX_VARIABLE_NUMBER before 3.32.0
+func (sqliteDialect) MaxArgs() int {
+	return 999
+}
+
+func (sqliteDialect) Limit(limit int, offset int) string {
+	if offset > 0 {
+		// OFFSET need LIMIT, -1 means no limit
+		return "LIMIT " + strconv.Itoa(limit) + " OFFSET " + strconv.Itoa(offset)
+	}
+	if limit >= 0 {
+		return "LIMIT " + strconv.Itoa(limit)
+	}
+	return ""
+}
+
+type sqlserverDialect struct{}
+
+func (sqlserverDialect) Name() string {
+	return "sqlserver"
+}
+
+func (sqlserverDialect) Placeholder(n int) string {
+	return "@p" + strconv.Itoa(n)
+}
+
+func (sqlserverDialect) Quote(ident string) string {
+	return quote(ident, "[", "]")
+}
+
+// 2100 include sp_executesql own params
+func (sqlserverDialect) MaxArgs() int {
+	return 2098
+}
+
+// OFFSET FETCH need ORDER BY before it, Query write ORDER BY (SELECT NULL) if none
+func (sqlserverDialect) Limit(limit int, offset int) string {
+	if limit < 0 && offset <= 0 {
+		return ""
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	s := "OFFSET " + strconv.Itoa(offset) + " ROWS"
+	if limit >= 0 {
+		s += " FETCH NEXT " + strconv.Itoa(limit) + " ROWS ONLY"
+	}
+	return s
+}
+
+// escape right quote by doubling it
+func quote(ident string, left string, right string) string {
+	return left + strings.Replace(ident, right, right+right, -1) + right
+}
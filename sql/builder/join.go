@@ -0,0 +1,90 @@
+// join with table alias, column like u.id is quoted as `u`.`id`
+// builder.SelectJoin("user u", []builder.Join{
+//     builder.LeftJoin("orders o", builder.Where{"o.uid": builder.Col("u.id")}),
+// }, builder.Where{"u.id >": 1, "_field": "u.name,o.amount"})
+// SELECT `u`.`name`,`o`.`amount` FROM `user` AS `u` LEFT JOIN `orders` AS `o` ON (`o`.`uid` = `u`.`id`) WHERE (`u`.`id` > ?)
+//
+// builder.From("user u").Columns("u.name", "o.amount").Join("orders o", builder.Where{"o.uid": builder.Col("u.id")})
+
+package builder
+
+import (
+	"strings"
+)
+
+type Join struct {
+	// INNER LEFT RIGHT
+	Type  string
+	Table string
+	// Where or Cond, joined by and
+	On []interface{}
+}
+
+// column as value in Where, not an arg
+type Col string
+
+func InnerJoin(table string, on ...interface{}) Join {
+	return Join{Type: "INNER", Table: table, On: on}
+}
+
+func LeftJoin(table string, on ...interface{}) Join {
+	return Join{Type: "LEFT", Table: table, On: on}
+}
+
+func RightJoin(table string, on ...interface{}) Join {
+	return Join{Type: "RIGHT", Table: table, On: on}
+}
+
+func SelectJoin(table string, joins []Join, where Where) (string, []interface{}) {
+	return Default.SelectJoin(table, joins, where)
+}
+
+// magic keys of where are same as Select
+func (b *Builder) SelectJoin(table string, joins []Join, where Where) (string, []interface{}) {
+	q := b.fromWhere(table, where)
+	q.joins = joins
+	return q.Sql()
+}
+
+func (q *Query) Join(table string, on ...interface{}) *Query {
+	return q.join(InnerJoin(table, on...))
+}
+
+func (q *Query) LeftJoin(table string, on ...interface{}) *Query {
+	return q.join(LeftJoin(table, on...))
+}
+
+func (q *Query) RightJoin(table string, on ...interface{}) *Query {
+	return q.join(RightJoin(table, on...))
+}
+
+func (q *Query) join(j Join) *Query {
+	c := q.clone()
+	c.joins = append(append([]Join{}, q.joins...), j)
+	return c
+}
+
+func (s *stmt) joins(joins []Join) {
+	for _, j := range joins {
+		s.WriteString(" ")
+		s.WriteString(j.Type)
+		s.WriteString(" JOIN ")
+		s.table(j.Table)
+		s.clause(" ON ", j.On)
+	}
+}
+
+// "table", "table alias" or "table as alias"
+func (s *stmt) table(table string) {
+	f := strings.Fields(table)
+	if len(f) == 3 && strings.EqualFold(f[1], "as") {
+		f = []string{f[0], f[2]}
+	}
+	if len(f) != 2 {
+		s.quote(table)
+		return
+	}
+	s.quote(f[0])
+	s.WriteString(" AS ")
+	s.quote(f[1])
+}
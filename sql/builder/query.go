@@ -0,0 +1,309 @@
+// chainable query, every call return a new Query and never modify inputs
+// q := builder.From("test").Columns("id", "name").Where(builder.Where{"id >": 1}).OrderBy("id desc").Limit(10)
+// sql, args := q.Sql()
+// SELECT `id`,`name` FROM `test` WHERE (`id` > ?) ORDER BY `id` desc LIMIT 10
+// same sql for same query, Where keys are sorted
+
+package builder
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Query struct {
+	dialect Dialect
+	table   string
+	joins   []Join
+	columns []string
+	where   []interface{}
+	groupby []string
+	having  []interface{}
+	orderby []string
+	other   string
+	limit   int
+	offset  int
+}
+
+func From(table string) *Query {
+	return Default.From(table)
+}
+
+func (b *Builder) From(table string) *Query {
+	return &Query{
+		dialect: b.dialect,
+		table:   table,
+		limit:   -1,
+	}
+}
+
+func (q *Query) clone() *Query {
+	c := *q
+	return &c
+}
+
+// column name is quoted, others like count(*) are not
+func (q *Query) Columns(columns ...string) *Query {
+	c := q.clone()
+	c.columns = appendStrings(q.columns, columns)
+	return c
+}
+
+// Where or Cond, joined by and with previous
+func (q *Query) Where(conds ...interface{}) *Query {
+	c := q.clone()
+	c.where = appendItems(q.where, conds)
+	return c
+}
+
+func (q *Query) GroupBy(columns ...string) *Query {
+	c := q.clone()
+	c.groupby = appendStrings(q.groupby, columns)
+	return c
+}
+
+func (q *Query) Having(conds ...interface{}) *Query {
+	c := q.clone()
+	c.having = appendItems(q.having, conds)
+	return c
+}
+
+// column with optional asc or desc, like "id desc"
+func (q *Query) OrderBy(columns ...string) *Query {
+	c := q.clone()
+	c.orderby = appendStrings(q.orderby, columns)
+	return c
+}
+
+func (q *Query) Limit(limit int) *Query {
+	c := q.clone()
+	c.limit = limit
+	return c
+}
+
+func (q *Query) Offset(offset int) *Query {
+	c := q.clone()
+	c.offset = offset
+	return c
+}
+
+// SELECT
+func (q *Query) Sql() (string, []interface{}) {
+	s := &stmt{dialect: q.dialect}
+	q.selectTo(s)
+	return s.String(), s.args
+}
+
+// also for subquery, which use dialect of outer query
+func (q *Query) selectTo(s *stmt) {
+	s.WriteString("SELECT ")
+	if len(q.columns) == 0 {
+		s.WriteString("*")
+	} else {
+		s.columns(q.columns)
+	}
+	s.WriteString(" FROM ")
+	s.table(q.table)
+	s.joins(q.joins)
+
+	s.clause(" WHERE ", q.where)
+	if len(q.groupby) > 0 {
+		s.WriteString(" GROUP BY ")
+		s.columns(q.groupby)
+	}
+	s.clause(" HAVING ", q.having)
+	q.tail(s)
+}
+
+// error if Limit or Offset is set and not supported by dialect
+func (q *Query) Update(values Values) (string, []interface{}, error) {
+	s := &stmt{dialect: q.dialect}
+	s.WriteString("UPDATE ")
+	s.table(q.table)
+	s.WriteString(" SET ")
+	s.set(values)
+
+	s.clause(" WHERE ", q.where)
+	if err := q.writeTail(s); err != nil {
+		return "", nil, err
+	}
+	return s.String(), s.args, nil
+}
+
+// error if Limit or Offset is set and not supported by dialect
+func (q *Query) Delete() (string, []interface{}, error) {
+	s := &stmt{dialect: q.dialect}
+	s.WriteString("DELETE FROM ")
+	s.table(q.table)
+
+	s.clause(" WHERE ", q.where)
+	if err := q.writeTail(s); err != nil {
+		return "", nil, err
+	}
+	return s.String(), s.args, nil
+}
+
+// tail of UPDATE and DELETE, only mysql has LIMIT for them and without OFFSET
+func (q *Query) writeTail(s *stmt) error {
+	if q.limit >= 0 || q.offset > 0 {
+		if name := s.dialect.Name(); name != "mysql" || q.offset > 0 {
+			return fmt.Errorf("limit offset of update and delete is not supported by %s", name)
+		}
+	}
+	q.tail(s)
+	return nil
+}
+
+// orderby, raw sql of _other, limit offset
+func (q *Query) tail(s *stmt) {
+	limit := s.dialect.Limit(q.limit, q.offset)
+	if len(q.orderby) > 0 {
+		s.WriteString(" ORDER BY ")
+		s.columns(q.orderby)
+	} else if limit != "" && s.dialect == SQLServer && !strings.Contains(strings.ToLower(q.other), "order by") {
+		// OFFSET FETCH is invalid without ORDER BY, keep the order as is
+		s.WriteString(" ORDER BY (SELECT NULL)")
+	}
+	if q.other != "" {
+		s.WriteString(" ")
+		s.WriteString(q.other)
+	}
+	if limit != "" {
+		s.WriteString(" ")
+		s.WriteString(limit)
+	}
+}
+
+// Query from Where with magic keys, where is not modified
+func (b *Builder) fromWhere(table string, where Where) *Query {
+	q := b.From(table)
+	cond := Where{}
+	for k, v := range where {
+		switch k {
+		case "_field":
+			q.columns = splitColumns(v.(string))
+		case "_groupby":
+			q.groupby = splitColumns(v.(string))
+		case "_having":
+			q.having = []interface{}{v}
+		case "_other":
+			q.other = v.(string)
+		case "_limit":
+			q.limit = v.(int)
+		case "_offset":
+			q.offset = v.(int)
+		default:
+			cond[k] = v
+		}
+	}
+	if len(cond) > 0 {
+		q.where = []interface{}{cond}
+	}
+	return q
+}
+
+// keyword and conds, nothing if no item or all are empty Where
+func (s *stmt) clause(keyword string, items []interface{}) {
+	items = nonEmpty(items)
+	if len(items) == 0 {
+		return
+	}
+	s.WriteString(keyword)
+	s.conds(items)
+}
+
+// top level Where is not wrapped by ()
+func (s *stmt) conds(items []interface{}) {
+	for i, item := range items {
+		if i > 0 {
+			s.WriteString(" and ")
+		}
+		if _, ok := item.(Cond); ok {
+			s.item(item)
+		} else {
+			s.where(toWhere(item))
+		}
+	}
+}
+
+func (s *stmt) columns(columns []string) {
+	for i, col := range columns {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.column(col)
+	}
+}
+
+// quote name part of "name [asc|desc]" or "name as alias", others are raw sql
+func (s *stmt) column(col string) {
+	name, rest, ok := columnName(col)
+	if !ok {
+		s.WriteString(col)
+		return
+	}
+	s.quote(name)
+	s.WriteString(rest)
+}
+
+// name and rest of a plain column, false for others like "DISTINCT name"
+func columnName(col string) (string, string, bool) {
+	col = strings.TrimSpace(col)
+	f := strings.Fields(col)
+	if len(f) == 0 || !isIdent(f[0]) {
+		return "", "", false
+	}
+	switch len(f) {
+	case 1:
+	case 2:
+		if dir := strings.ToLower(f[1]); dir != "asc" && dir != "desc" {
+			return "", "", false
+		}
+	case 3:
+		if strings.ToLower(f[1]) != "as" || strings.Contains(f[2], ".") || !isIdent(f[2]) {
+			return "", "", false
+		}
+	default:
+		return "", "", false
+	}
+	return f[0], col[len(f[0]):], true
+}
+
+// "u.id,u.name" to each column, not split if any is not plain column like count(a,b)
+func splitColumns(field string) []string {
+	columns := strings.Split(field, ",")
+	for i, col := range columns {
+		if _, _, ok := columnName(col); !ok {
+			return []string{field}
+		}
+		columns[i] = strings.TrimSpace(col)
+	}
+	return columns
+}
+
+// name, table.name or table.*
+func isIdent(name string) bool {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "*" && i > 0 && i == len(parts)-1 {
+			continue
+		}
+		if part == "" {
+			return false
+		}
+		for j, c := range part {
+			if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || j > 0 && c >= '0' && c <= '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func appendStrings(a []string, b []string) []string {
+	return append(append([]string{}, a...), b...)
+}
+
+func appendItems(a []interface{}, b []interface{}) []interface{} {
+	return append(append([]interface{}{}, a...), b...)
+}
@@ -0,0 +1,53 @@
+// subquery as value in Where, or in Exists and InsertSelect
+// builder.Where{"id in": builder.From("orders").Columns("uid").Where(builder.Where{"amount >": 100})}
+// (`id` in (SELECT `uid` FROM `orders` WHERE (`amount` > ?)))
+// subquery use dialect of outer sql, args are merged in order
+
+package builder
+
+type exists struct {
+	query *Query
+	not   bool
+}
+
+func Exists(q *Query) Cond {
+	return exists{query: q}
+}
+
+func NotExists(q *Query) Cond {
+	return exists{query: q, not: true}
+}
+
+func (c exists) cond(s *stmt) {
+	if c.not {
+		s.WriteString("(NOT EXISTS (")
+	} else {
+		s.WriteString("(EXISTS (")
+	}
+	c.query.selectTo(s)
+	s.WriteString("))")
+}
+
+func InsertSelect(table string, columns []string, q *Query) (string, []interface{}) {
+	return Default.InsertSelect(table, columns, q)
+}
+
+// INSERT INTO table(columns) SELECT ..., all columns of table if columns is empty
+func (b *Builder) InsertSelect(table string, columns []string, q *Query) (string, []interface{}) {
+	s := b.stmt()
+	s.WriteString("INSERT INTO ")
+	s.quote(table)
+	if len(columns) > 0 {
+		s.WriteString("(")
+		for i, col := range columns {
+			if i > 0 {
+				s.WriteString(",")
+			}
+			s.quote(col)
+		}
+		s.WriteString(")")
+	}
+	s.WriteString(" ")
+	q.selectTo(s)
+	return s.String(), s.args
+}
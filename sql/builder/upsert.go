@@ -0,0 +1,122 @@
+// insert or update on conflict
+// builder.Upsert("test", builder.Values{"id": 1, "name": "a", "n": 1}, []string{"id"}, builder.Values{
+//     "name": builder.Inserted,
+//     "n":    0,
+// })
+// mysql:  ... ON DUPLICATE KEY UPDATE `n`=?,`name`=VALUES(`name`)
+// others: ... ON CONFLICT ("id") DO UPDATE SET "n"=$4,"name"=excluded."name"
+// empty updateValues means insert ignore
+
+package builder
+
+import (
+	"fmt"
+	"sort"
+)
+
+type inserted struct{}
+
+// update column with value of the row being inserted
+var Inserted = inserted{}
+
+func Upsert(table string, values Values, conflictKeys []string, updateValues Values) (string, []interface{}, error) {
+	return Default.Upsert(table, values, conflictKeys, updateValues)
+}
+
+// conflictKeys is not used by mysql, which use all unique keys
+func (b *Builder) Upsert(table string, values Values, conflictKeys []string, updateValues Values) (string, []interface{}, error) {
+	if len(values) == 0 {
+		return "", nil, fmt.Errorf("no values to insert")
+	}
+
+	name := b.dialect.Name()
+	if name != "mysql" && name != "postgres" && name != "sqlite3" {
+		return "", nil, fmt.Errorf("upsert is not supported by %s", name)
+	}
+	if name != "mysql" && len(updateValues) > 0 && len(conflictKeys) == 0 {
+		return "", nil, fmt.Errorf("upsert need conflict keys for %s", name)
+	}
+
+	s := b.stmt()
+	if name == "mysql" && len(updateValues) == 0 {
+		s.WriteString("INSERT IGNORE INTO ")
+	} else {
+		s.WriteString("INSERT INTO ")
+	}
+	s.quote(table)
+
+	cols := sortedKeys(values)
+	s.WriteString("(")
+	for i, k := range cols {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.quote(k)
+	}
+	s.WriteString(") VALUES(")
+	for i, k := range cols {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.arg(values[k])
+	}
+	s.WriteString(")")
+
+	if name == "mysql" {
+		if len(updateValues) > 0 {
+			s.WriteString(" ON DUPLICATE KEY UPDATE ")
+			s.upsertSet(updateValues, func(k string) {
+				s.WriteString("VALUES(")
+				s.quote(k)
+				s.WriteString(")")
+			})
+		}
+		return s.String(), s.args, nil
+	}
+
+	s.WriteString(" ON CONFLICT ")
+	if len(conflictKeys) > 0 {
+		s.WriteString("(")
+		for i, k := range conflictKeys {
+			if i > 0 {
+				s.WriteString(",")
+			}
+			s.quote(k)
+		}
+		s.WriteString(") ")
+	}
+	if len(updateValues) == 0 {
+		s.WriteString("DO NOTHING")
+		return s.String(), s.args, nil
+	}
+	s.WriteString("DO UPDATE SET ")
+	s.upsertSet(updateValues, func(k string) {
+		s.WriteString("excluded.")
+		s.quote(k)
+	})
+	return s.String(), s.args, nil
+}
+
+func (s *stmt) upsertSet(values Values, inserted func(k string)) {
+	for i, k := range sortedKeys(values) {
+		if i > 0 {
+			s.WriteString(",")
+		}
+		s.quote(k)
+		s.WriteString("=")
+		if values[k] == Inserted {
+			inserted(k)
+		} else {
+			s.arg(values[k])
+		}
+	}
+}
+
+func sortedKeys(values Values) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,113 @@
+// read from replica, write to primary
+// sql.Install(sql.DBConf{
+//     "testdb": []string{"mysql", "primary dsn", "replica1 dsn", "replica2 dsn"},
+// })
+// Query* and Select* go to a healthy replica, Exec Insert Update Delete and Tx go to primary
+// db.Primary().Select(...) to read after write
+
+package sql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/JoveYu/zgo/log"
+)
+
+// policy to choose replica
+const (
+	ReplicaRoundRobin = iota
+	ReplicaLeastInUse
+)
+
+// replica is skipped for this time after connection error
+var ReplicaDownTime = 10 * time.Second
+
+type replicaSet struct {
+	dbs    []*DB
+	next   uint32
+	policy int32
+	// unix nano until replica is healthy, same index as dbs
+	down []int64
+}
+
+func (d *DB) SetReplicaPolicy(policy int) {
+	if d.replicas != nil {
+		atomic.StoreInt32(&d.replicas.policy, int32(policy))
+	}
+}
+
+// same DB without replica, all read go to primary
+func (d *DB) Primary() *DB {
+	p := *d
+	p.replicas = nil
+	p.DBTool = &DBTool{db: &p}
+	return &p
+}
+
+// close primary and replicas
+func (d *DB) Close() error {
+	err := d.DB.Close()
+	if d.replicas != nil {
+		for _, r := range d.replicas.dbs {
+			if e := r.DB.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	return err
+}
+
+// nil if no healthy replica
+func (d *DB) replica() *DB {
+	rs := d.replicas
+	if rs == nil {
+		return nil
+	}
+
+	now := time.Now().UnixNano()
+	n := len(rs.dbs)
+	// modulo in uint32, int may be negative on 32-bit
+	start := atomic.AddUint32(&rs.next, 1)
+	policy := atomic.LoadInt32(&rs.policy)
+
+	var best *DB
+	inuse := 0
+	for i := 0; i < n; i++ {
+		idx := int((start + uint32(i)) % uint32(n))
+		if atomic.LoadInt64(&rs.down[idx]) > now {
+			continue
+		}
+		r := rs.dbs[idx]
+		if policy == ReplicaRoundRobin {
+			return r
+		}
+		if use := r.DB.Stats().InUse; best == nil || use < inuse {
+			best, inuse = r, use
+		}
+	}
+	return best
+}
+
+func (d *DB) markDown(replica *DB, err error) {
+	for i, r := range d.replicas.dbs {
+		if r == replica {
+			atomic.StoreInt64(&d.replicas.down[i], time.Now().Add(ReplicaDownTime).UnixNano())
+			log.Get("sql").Warnw("", "ep", r.driver, "name", r.name, "func", "down", "err", err)
+		}
+	}
+}
+
+// replica may be down, try primary if ctx is still live
+// context error is also net.Error, which is not replica down
+func isConnError(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
@@ -1,5 +1,5 @@
 // use sql not orm
-// use simple sql not join
+// use simple sql, join by SelectJoin if need
 
 // use go sql just like python dbpool.py
 // ref : https://github.com/JoveYu/zpy/blob/master/base/dbpool.py
@@ -11,6 +11,7 @@ package sql
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 	"time"
 
@@ -31,116 +32,166 @@ type DBTool struct {
 type DB struct {
 	*DBTool
 	*sql.DB
-	name   string
-	driver string
-	dsn    string
+	name     string
+	driver   string
+	dsn      string
+	builder  *builder.Builder
+	replicas *replicaSet
 }
 
 type Tx struct {
 	*DBTool
 	*sql.Tx
 	db *DB
+	// ctx of BeginTx with this Tx, see Context
+	ctx context.Context
+	// depth of nested WithTx
+	savepoint int
 }
 
 type Where builder.Where
 type Values builder.Values
 type DBConf map[string][]string
 
+// for Upsert update value
+var Inserted = builder.Inserted
+
+// condition in Where, see builder.Cond
+type And = builder.And
+type Or = builder.Or
+type Not = builder.Not
+
+var Raw = builder.Raw
+
+// join for SelectJoin, see builder.Join
+type Join = builder.Join
+type Col = builder.Col
+
+var (
+	InnerJoin = builder.InnerJoin
+	LeftJoin  = builder.LeftJoin
+	RightJoin = builder.RightJoin
+)
+
+// subquery, see builder.Query
+type Query = builder.Query
+
+var (
+	From      = builder.From
+	Exists    = builder.Exists
+	NotExists = builder.NotExists
+)
+
 func Install(conf DBConf) map[string]DB {
-	log.Debug("available sql driver: %s", sql.Drivers())
+	log.Get("sql").Debug("available sql driver: %s", sql.Drivers())
 	for k, v := range conf {
-		if len(v) != 2 {
-			log.Fatal("parse db config error")
-		}
-		db, err := sql.Open(v[0], v[1])
-		if err != nil {
-			log.Fatal("%s", err)
+		// driver, primary dsn, replica dsn...
+		if len(v) < 2 {
+			log.Get("sql").Fatal("parse db config error")
 		}
 
-		// escape password
-		dsn := v[1]
-		start := strings.IndexByte(dsn, ':')
-		end := strings.IndexByte(dsn, '@')
-		if start > 0 && end > 0 {
-			dsn = dsn[:start+1] + "***" + dsn[end:]
+		zdb := openDB(k, v[0], v[1])
+		if len(v) > 2 {
+			zdb.replicas = &replicaSet{}
+			for i, dsn := range v[2:] {
+				replica := openDB(fmt.Sprintf("%s:replica%d", k, i+1), v[0], dsn)
+				zdb.replicas.dbs = append(zdb.replicas.dbs, replica)
+			}
+			zdb.replicas.down = make([]int64, len(zdb.replicas.dbs))
 		}
+		dbMap[k] = *zdb
+	}
+	return dbMap
+}
 
-		zdb := DB{
-			DB:     db,
-			name:   k,
-			driver: v[0],
-			dsn:    dsn,
-		}
-		zdb.DBTool = &DBTool{db: &zdb}
+func openDB(name string, driver string, dsn string) *DB {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		log.Get("sql").Fatal("%s", err)
+	}
 
-		dbMap[k] = zdb
-		log.Info("ep=%s|func=install|name=%s|conf=%s", zdb.driver, zdb.name, zdb.dsn)
+	// escape password
+	start := strings.IndexByte(dsn, ':')
+	end := strings.IndexByte(dsn, '@')
+	if start > 0 && end > 0 {
+		dsn = dsn[:start+1] + "***" + dsn[end:]
 	}
-	return dbMap
+
+	zdb := DB{
+		DB:      db,
+		name:    name,
+		driver:  driver,
+		dsn:     dsn,
+		builder: builder.New(builder.DialectOf(driver)),
+	}
+	zdb.DBTool = &DBTool{db: &zdb}
+
+	log.Get("sql").Infow("", "ep", zdb.driver, "func", "install", "name", zdb.name, "dialect", zdb.builder.Dialect().Name(), "conf", zdb.dsn)
+	return &zdb
 }
 
 func GetDB(name string) *DB {
 	if db, ok := dbMap[name]; ok {
 		return &db
 	} else {
-		log.Error("can not get db [%s]", name)
+		log.Get("sql").Error("can not get db [%s]", name)
 		return nil
 	}
 }
 
 func (t *Tx) Exec(query string, args ...interface{}) (result sql.Result, err error) {
-	defer t.db.timeit(time.Now(), &err, true, query, args...)
+	defer t.db.timeit(context.Background(), time.Now(), &err, true, query, args...)
 
 	result, err = t.Tx.Exec(query, args...)
 	return
 }
 
 func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
-	defer t.db.timeit(time.Now(), &err, true, query, args...)
+	defer t.db.timeit(ctx, time.Now(), &err, true, query, args...)
 
 	result, err = t.Tx.ExecContext(ctx, query, args...)
 	return
 }
 
 func (t *Tx) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
-	defer t.db.timeit(time.Now(), &err, true, query, args...)
+	defer t.db.timeit(context.Background(), time.Now(), &err, true, query, args...)
 
 	rows, err = t.Tx.Query(query, args...)
 	return
 }
 
 func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
-	defer t.db.timeit(time.Now(), &err, true, query, args...)
+	defer t.db.timeit(ctx, time.Now(), &err, true, query, args...)
 
 	rows, err = t.Tx.QueryContext(ctx, query, args...)
 	return
 }
 
 func (t *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
-	defer t.db.timeit(time.Now(), nil, true, query, args...)
+	defer t.db.timeit(context.Background(), time.Now(), nil, true, query, args...)
 
 	return t.Tx.QueryRow(query, args...)
 }
 
 func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	defer t.db.timeit(time.Now(), nil, true, query, args...)
+	defer t.db.timeit(ctx, time.Now(), nil, true, query, args...)
 
 	return t.Tx.QueryRowContext(ctx, query, args...)
 }
 
 func (t *Tx) Commit() error {
 	d := t.db
-	log.Info("ep=%s|name=%s|func=commit", d.driver, d.name)
+	log.Get("sql").Infow("", "ep", d.driver, "name", d.name, "func", "commit")
 	return t.Tx.Commit()
 }
 
 func (t *Tx) Rollback() error {
 	d := t.db
-	log.Info("ep=%s|name=%s|func=rollback", d.driver, d.name)
+	log.Get("sql").Infow("", "ep", d.driver, "name", d.name, "func", "rollback")
 	return t.Tx.Rollback()
 }
 
-func (d *DB) timeit(start time.Time, err *error, trans bool, query string, args ...interface{}) {
+func (d *DB) timeit(ctx context.Context, start time.Time, err *error, trans bool, query string, args ...interface{}) {
 	stat := d.DB.Stats()
 	duration := time.Since(start)
 
@@ -149,68 +200,95 @@ func (d *DB) timeit(start time.Time, err *error, trans bool, query string, args
 		t = 1
 	}
 
-	if *err == nil {
-		log.Info("ep=%s|name=%s|use=%d|idle=%d|max=%d|wait=%d|waittime=%d|time=%d|trans=%d|sql=%s|err=",
-			d.driver, d.name, stat.InUse, stat.Idle, stat.MaxOpenConnections, stat.WaitCount,
-			stat.WaitDuration/time.Microsecond, duration/time.Microsecond, t,
-			builder.FormatSql(query, args...),
-		)
+	fields := log.KV(
+		"ep", d.driver, "name", d.name,
+		"use", stat.InUse, "idle", stat.Idle, "max", stat.MaxOpenConnections,
+		"wait", stat.WaitCount, "waittime", int64(stat.WaitDuration/time.Microsecond),
+		"time", int64(duration/time.Microsecond), "trans", t,
+		"sql", d.builder.FormatSql(query, args...),
+	)
+
+	// QueryRow has no err
+	if err == nil || *err == nil {
+		log.Get("sql").Infow("", log.ContextFields(ctx), fields, "err", "")
 	} else {
-		log.Warn("ep=%s|name=%s|use=%d|idle=%d|max=%d|wait=%d|waittime=%d|time=%d|trans=%d|sql=%s|err=%s",
-			d.driver, d.name, stat.InUse, stat.Idle, stat.MaxOpenConnections, stat.WaitCount,
-			stat.WaitDuration/time.Microsecond, duration/time.Microsecond, t,
-			builder.FormatSql(query, args...), *err,
-		)
+		log.Get("sql").Warnw("", log.ContextFields(ctx), fields, "err", *err)
 	}
 }
 
+// sql builder with dialect of driver
+func (d *DB) Builder() *builder.Builder {
+	return d.builder
+}
+
 func (d *DB) Begin() (*Tx, error) {
-	log.Info("ep=%s|name=%s|func=begin", d.driver, d.name)
+	log.Get("sql").Infow("", "ep", d.driver, "name", d.name, "func", "begin")
 	tx, err := d.DB.Begin()
 	ztx := Tx{
 		Tx: tx,
 		db: d,
 	}
 	ztx.DBTool = &DBTool{tx: &ztx}
+	ztx.ctx = context.WithValue(context.Background(), txKey{}, &ztx)
 	return &ztx, err
 }
 
 func (d *DB) Exec(query string, args ...interface{}) (result sql.Result, err error) {
-	defer d.timeit(time.Now(), &err, false, query, args...)
+	defer d.timeit(context.Background(), time.Now(), &err, false, query, args...)
 
 	result, err = d.DB.Exec(query, args...)
 	return
 }
 
 func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (result sql.Result, err error) {
-	defer d.timeit(time.Now(), &err, false, query, args...)
+	defer d.timeit(ctx, time.Now(), &err, false, query, args...)
 
 	result, err = d.DB.ExecContext(ctx, query, args...)
 	return
 }
 
 func (d *DB) Query(query string, args ...interface{}) (rows *sql.Rows, err error) {
-	defer d.timeit(time.Now(), &err, false, query, args...)
+	if r := d.replica(); r != nil {
+		rows, err = r.Query(query, args...)
+		if !isConnError(context.Background(), err) {
+			return
+		}
+		d.markDown(r, err)
+	}
+	defer d.timeit(context.Background(), time.Now(), &err, false, query, args...)
 
 	rows, err = d.DB.Query(query, args...)
 	return
 }
 
 func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
-	defer d.timeit(time.Now(), &err, false, query, args...)
+	if r := d.replica(); r != nil {
+		rows, err = r.QueryContext(ctx, query, args...)
+		if !isConnError(ctx, err) {
+			return
+		}
+		d.markDown(r, err)
+	}
+	defer d.timeit(ctx, time.Now(), &err, false, query, args...)
 
 	rows, err = d.DB.QueryContext(ctx, query, args...)
 	return
 }
 
 func (d *DB) QueryRow(query string, args ...interface{}) *sql.Row {
-	defer d.timeit(time.Now(), nil, false, query, args...)
+	if r := d.replica(); r != nil {
+		return r.QueryRow(query, args...)
+	}
+	defer d.timeit(context.Background(), time.Now(), nil, false, query, args...)
 
 	return d.DB.QueryRow(query, args...)
 }
 
 func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
-	defer d.timeit(time.Now(), nil, false, query, args...)
+	if r := d.replica(); r != nil {
+		return r.QueryRowContext(ctx, query, args...)
+	}
+	defer d.timeit(ctx, time.Now(), nil, false, query, args...)
 
 	return d.DB.QueryRowContext(ctx, query, args...)
 }
@@ -260,12 +338,40 @@ func (d *DBTool) QueryContextScan(ctx context.Context, obj interface{}, query st
 }
 
 func (d *DBTool) SelectScan(obj interface{}, table string, where Where) error {
-	sql, args := builder.Select(table, d.escapeWhere(where))
+	sql, args := d.sqlBuilder().Select(table, d.escapeWhere(where))
 	return d.QueryScan(obj, sql, args...)
 }
 
 func (d *DBTool) SelectContextScan(ctx context.Context, obj interface{}, table string, where Where) error {
-	sql, args := builder.Select(table, d.escapeWhere(where))
+	sql, args := d.sqlBuilder().Select(table, d.escapeWhere(where))
+	return d.QueryContextScan(ctx, obj, sql, args...)
+}
+
+func (d *DBTool) SelectJoin(table string, joins []Join, where Where) (*sql.Rows, error) {
+	sql, args := d.sqlBuilder().SelectJoin(table, joins, d.escapeWhere(where))
+	if d.tx != nil {
+		return d.tx.Query(sql, args...)
+	} else {
+		return d.db.Query(sql, args...)
+	}
+}
+
+func (d *DBTool) SelectJoinContext(ctx context.Context, table string, joins []Join, where Where) (*sql.Rows, error) {
+	sql, args := d.sqlBuilder().SelectJoin(table, joins, d.escapeWhere(where))
+	if d.tx != nil {
+		return d.tx.QueryContext(ctx, sql, args...)
+	} else {
+		return d.db.QueryContext(ctx, sql, args...)
+	}
+}
+
+func (d *DBTool) SelectJoinScan(obj interface{}, table string, joins []Join, where Where) error {
+	sql, args := d.sqlBuilder().SelectJoin(table, joins, d.escapeWhere(where))
+	return d.QueryScan(obj, sql, args...)
+}
+
+func (d *DBTool) SelectJoinContextScan(ctx context.Context, obj interface{}, table string, joins []Join, where Where) error {
+	sql, args := d.sqlBuilder().SelectJoin(table, joins, d.escapeWhere(where))
 	return d.QueryContextScan(ctx, obj, sql, args...)
 }
 
@@ -313,12 +419,12 @@ func (d *DBTool) QueryMap(query string, args ...interface{}) (data []map[string]
 
 // TODO
 func (d *DBTool) SelectMap(table string, where Where) ([]map[string]interface{}, error) {
-	sql, args := builder.Select(table, d.escapeWhere(where))
+	sql, args := d.sqlBuilder().Select(table, d.escapeWhere(where))
 	return d.QueryMap(sql, args...)
 }
 
 func (d *DBTool) Select(table string, where Where) (*sql.Rows, error) {
-	sql, args := builder.Select(table, d.escapeWhere(where))
+	sql, args := d.sqlBuilder().Select(table, d.escapeWhere(where))
 	if d.tx != nil {
 		return d.tx.Query(sql, args...)
 	} else {
@@ -327,7 +433,7 @@ func (d *DBTool) Select(table string, where Where) (*sql.Rows, error) {
 }
 
 func (d *DBTool) SelectContext(ctx context.Context, table string, where Where) (*sql.Rows, error) {
-	sql, args := builder.Select(table, d.escapeWhere(where))
+	sql, args := d.sqlBuilder().Select(table, d.escapeWhere(where))
 	if d.tx != nil {
 		return d.tx.QueryContext(ctx, sql, args...)
 	} else {
@@ -336,7 +442,7 @@ func (d *DBTool) SelectContext(ctx context.Context, table string, where Where) (
 }
 
 func (d *DBTool) Insert(table string, value Values) (sql.Result, error) {
-	sql, args := builder.Insert(table, builder.Values(value))
+	sql, args := d.sqlBuilder().Insert(table, builder.Values(value))
 
 	if d.tx != nil {
 		return d.tx.Exec(sql, args...)
@@ -346,7 +452,96 @@ func (d *DBTool) Insert(table string, value Values) (sql.Result, error) {
 }
 
 func (d *DBTool) InsertContext(ctx context.Context, table string, value Values) (sql.Result, error) {
-	sql, args := builder.Insert(table, builder.Values(value))
+	sql, args := d.sqlBuilder().Insert(table, builder.Values(value))
+
+	if d.tx != nil {
+		return d.tx.ExecContext(ctx, sql, args...)
+	} else {
+		return d.db.ExecContext(ctx, sql, args...)
+	}
+}
+
+// split rows into chunks under placeholder limit, return total rows affected
+func (d *DBTool) InsertMany(table string, values []Values) (int64, error) {
+	return d.InsertManyContext(context.Background(), table, values)
+}
+
+func (d *DBTool) InsertManyContext(ctx context.Context, table string, values []Values) (int64, error) {
+	rows := make([]builder.Values, len(values))
+	for i, v := range values {
+		rows[i] = builder.Values(v)
+	}
+	cols, err := builder.Columns(rows)
+	if err != nil {
+		return 0, err
+	}
+
+	b := d.sqlBuilder()
+	size := b.Dialect().MaxArgs() / len(cols)
+	if size == 0 {
+		size = 1
+	}
+
+	var total int64
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+		query, args, err := b.InsertMany(table, rows[start:end])
+		if err != nil {
+			return total, err
+		}
+
+		var result sql.Result
+		if d.tx != nil {
+			result, err = d.tx.ExecContext(ctx, query, args...)
+		} else {
+			result, err = d.db.ExecContext(ctx, query, args...)
+		}
+		if err != nil {
+			return total, err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// insert or update on conflict, insert ignore if updateValues is empty
+func (d *DBTool) Upsert(table string, value Values, conflictKeys []string, updateValues Values) (sql.Result, error) {
+	return d.UpsertContext(context.Background(), table, value, conflictKeys, updateValues)
+}
+
+func (d *DBTool) UpsertContext(ctx context.Context, table string, value Values, conflictKeys []string, updateValues Values) (sql.Result, error) {
+	query, args, err := d.sqlBuilder().Upsert(table, builder.Values(value), conflictKeys, builder.Values(updateValues))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.tx != nil {
+		return d.tx.ExecContext(ctx, query, args...)
+	} else {
+		return d.db.ExecContext(ctx, query, args...)
+	}
+}
+
+// INSERT INTO table(columns) SELECT ...
+func (d *DBTool) InsertSelect(table string, columns []string, q *Query) (sql.Result, error) {
+	sql, args := d.sqlBuilder().InsertSelect(table, columns, q)
+
+	if d.tx != nil {
+		return d.tx.Exec(sql, args...)
+	} else {
+		return d.db.Exec(sql, args...)
+	}
+}
+
+func (d *DBTool) InsertSelectContext(ctx context.Context, table string, columns []string, q *Query) (sql.Result, error) {
+	sql, args := d.sqlBuilder().InsertSelect(table, columns, q)
 
 	if d.tx != nil {
 		return d.tx.ExecContext(ctx, sql, args...)
@@ -357,7 +552,10 @@ func (d *DBTool) InsertContext(ctx context.Context, table string, value Values)
 
 func (d *DBTool) Update(table string, value Values, where Where) (sql.Result, error) {
 
-	sql, args := builder.Update(table, builder.Values(value), d.escapeWhere(where))
+	sql, args, err := d.sqlBuilder().Update(table, builder.Values(value), d.escapeWhere(where))
+	if err != nil {
+		return nil, err
+	}
 
 	if d.tx != nil {
 		return d.tx.Exec(sql, args...)
@@ -367,7 +565,10 @@ func (d *DBTool) Update(table string, value Values, where Where) (sql.Result, er
 }
 func (d *DBTool) UpdateContext(ctx context.Context, table string, value Values, where Where) (sql.Result, error) {
 
-	sql, args := builder.Update(table, builder.Values(value), d.escapeWhere(where))
+	sql, args, err := d.sqlBuilder().Update(table, builder.Values(value), d.escapeWhere(where))
+	if err != nil {
+		return nil, err
+	}
 
 	if d.tx != nil {
 		return d.tx.ExecContext(ctx, sql, args...)
@@ -378,7 +579,10 @@ func (d *DBTool) UpdateContext(ctx context.Context, table string, value Values,
 
 func (d *DBTool) Delete(table string, where Where) (sql.Result, error) {
 
-	sql, args := builder.Delete(table, d.escapeWhere(where))
+	sql, args, err := d.sqlBuilder().Delete(table, d.escapeWhere(where))
+	if err != nil {
+		return nil, err
+	}
 
 	if d.tx != nil {
 		return d.tx.Exec(sql, args...)
@@ -388,7 +592,10 @@ func (d *DBTool) Delete(table string, where Where) (sql.Result, error) {
 }
 func (d *DBTool) DeleteContext(ctx context.Context, table string, where Where) (sql.Result, error) {
 
-	sql, args := builder.Delete(table, d.escapeWhere(where))
+	sql, args, err := d.sqlBuilder().Delete(table, d.escapeWhere(where))
+	if err != nil {
+		return nil, err
+	}
 
 	if d.tx != nil {
 		return d.tx.ExecContext(ctx, sql, args...)
@@ -397,9 +604,14 @@ func (d *DBTool) DeleteContext(ctx context.Context, table string, where Where) (
 	}
 }
 
-func (d *DBTool) escapeWhere(where Where) builder.Where {
-	if value, ok := where["_having"]; ok {
-		where["_having"] = builder.Where(value.(Where))
+func (d *DBTool) sqlBuilder() *builder.Builder {
+	if d.tx != nil {
+		return d.tx.db.builder
 	}
+	return d.db.builder
+}
+
+// nested sql.Where is accepted by builder
+func (d *DBTool) escapeWhere(where Where) builder.Where {
 	return builder.Where(where)
 }
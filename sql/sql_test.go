@@ -2,6 +2,9 @@ package sql
 
 import "fmt"
 import "context"
+import "database/sql"
+import "database/sql/driver"
+import "math"
 import "sync"
 import "time"
 import "testing"
@@ -170,3 +173,353 @@ func TestScan(t *testing.T) {
 	log.Debug(user1)
 
 }
+
+func TestInsertMany(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+
+	// more than one chunk for sqlite
+	values := []Values{}
+	for i := 0; i < 1000; i++ {
+		values = append(values, Values{
+			"id":   i,
+			"name": fmt.Sprintf("name %d", i),
+			"time": time.Now(),
+		})
+	}
+
+	tx, _ := db.Begin()
+	n, err := tx.InsertMany("test", values)
+	if err != nil || n != 1000 {
+		t.Errorf("insert many: %d %s", n, err)
+	}
+	tx.Rollback()
+
+	rows, _ := db.SelectMap("test", Where{"_field": "count(*) as n"})
+	if rows[0]["n"] != int64(0) {
+		t.Errorf("not in tx: %v", rows)
+	}
+
+	n, err = db.InsertMany("test", values)
+	if err != nil || n != 1000 {
+		t.Errorf("insert many: %d %s", n, err)
+	}
+
+	_, err = db.InsertMany("test", []Values{{"id": 1}, {"name": "b"}})
+	if err == nil {
+		t.Errorf("different columns not checked")
+	}
+}
+
+func TestUpsert(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+
+	db.Insert("test", Values{"id": 1, "name": "a"})
+
+	_, err := db.Upsert("test", Values{"id": 1, "name": "b"}, []string{"id"}, nil)
+	if err != nil {
+		t.Errorf("upsert ignore: %s", err)
+	}
+	rows, _ := db.SelectMap("test", Where{"id": 1})
+	if fmt.Sprintf("%s", rows[0]["name"]) != "a" {
+		t.Errorf("ignore updated: %v", rows)
+	}
+
+	_, err = db.Upsert("test", Values{"id": 1, "name": "c"}, []string{"id"}, Values{"name": Inserted})
+	if err != nil {
+		t.Errorf("upsert: %s", err)
+	}
+	rows, _ = db.SelectMap("test", Where{"id": 1})
+	if fmt.Sprintf("%s", rows[0]["name"]) != "c" {
+		t.Errorf("not updated: %v", rows)
+	}
+}
+
+func TestCond(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+	for i := 1; i <= 5; i++ {
+		db.Insert("test", Values{"id": i, "name": fmt.Sprintf("name %d", i)})
+	}
+
+	rows, err := db.SelectMap("test", Where{
+		"_or": Or{Where{"id": 1}, And{Where{"id >": 3}, Not{Where{"name": "name 5"}}}},
+	})
+	if err != nil || len(rows) != 2 {
+		t.Errorf("select or: %v %s", rows, err)
+	}
+
+	db.Delete("test", Where{"_raw": Raw("id = ? or id = ?", 1, 2)})
+	rows, _ = db.SelectMap("test", Where{})
+	if len(rows) != 3 {
+		t.Errorf("delete raw: %v", rows)
+	}
+}
+
+type Order struct {
+	Name   string `zdb:"name"`
+	Amount int    `zdb:"amount"`
+}
+
+func TestSelectJoin(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("drop table if exists orders")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+	db.Exec("create table if not exists orders(id integer not null primary key, uid integer, amount integer)")
+	for i := 1; i <= 3; i++ {
+		db.Insert("test", Values{"id": i, "name": fmt.Sprintf("name %d", i)})
+		db.Insert("orders", Values{"id": i, "uid": i, "amount": i * 100})
+	}
+
+	orders := []Order{}
+	err := db.SelectJoinScan(&orders, "test u", []Join{
+		InnerJoin("orders o", Where{"o.uid": Col("u.id")}),
+	}, Where{"o.amount >": 100, "_field": "u.name,o.amount", "_other": "order by o.amount"})
+	if err != nil || len(orders) != 2 || orders[0].Amount != 200 || orders[0].Name != "name 2" {
+		t.Errorf("select join: %v %s", orders, err)
+	}
+}
+
+func TestSubquery(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("drop table if exists orders")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+	db.Exec("create table if not exists orders(id integer not null primary key, uid integer, amount integer)")
+	for i := 1; i <= 3; i++ {
+		db.Insert("orders", Values{"id": i, "uid": i, "amount": i * 100})
+	}
+
+	_, err := db.InsertSelect("test", []string{"id", "name"}, From("orders").Columns("uid", "'name'").Where(Where{"amount >": 100}))
+	if err != nil {
+		t.Errorf("insert select: %s", err)
+	}
+
+	rows, err := db.SelectMap("test", Where{
+		"id in":   From("orders").Columns("uid").Where(Where{"amount": 300}),
+		"_exists": Exists(From("orders o").Where(Where{"o.uid": Col("test.id")})),
+	})
+	if err != nil || len(rows) != 1 || rows[0]["id"] != int64(3) {
+		t.Errorf("select subquery: %v %s", rows, err)
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"sqlite3": []string{"sqlite3", "file::memory:?mode=memory&cache=shared"},
+	})
+	db := GetDB("sqlite3")
+	db.Exec("drop table if exists test")
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+	ctx := context.Background()
+
+	count := func() int64 {
+		rows, _ := db.SelectMap("test", Where{"_field": "count(*) as n"})
+		return rows[0]["n"].(int64)
+	}
+
+	err := db.WithTx(ctx, nil, func(tx *Tx) error {
+		_, err := tx.Insert("test", Values{"id": 1, "name": "a"})
+		return err
+	})
+	if err != nil || count() != 1 {
+		t.Errorf("commit: %s %d", err, count())
+	}
+
+	err = db.WithTx(ctx, nil, func(tx *Tx) error {
+		tx.Insert("test", Values{"id": 2, "name": "b"})
+		return fmt.Errorf("fail")
+	})
+	if err == nil || count() != 1 {
+		t.Errorf("rollback: %s %d", err, count())
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic not raised")
+			}
+		}()
+		db.WithTx(ctx, nil, func(tx *Tx) error {
+			tx.Insert("test", Values{"id": 2, "name": "b"})
+			panic("boom")
+		})
+	}()
+	if count() != 1 {
+		t.Errorf("panic not rollback: %d", count())
+	}
+
+	// nested rollback only to savepoint
+	err = db.WithTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *Tx) error {
+		tx.Insert("test", Values{"id": 2, "name": "b"})
+		tx.WithTx(ctx, func(tx *Tx) error {
+			tx.Insert("test", Values{"id": 3, "name": "c"})
+			return fmt.Errorf("fail")
+		})
+		return tx.WithTx(ctx, func(tx *Tx) error {
+			_, err := tx.Insert("test", Values{"id": 4, "name": "d"})
+			return err
+		})
+	})
+	rows, _ := db.SelectMap("test", Where{"_field": "id", "_other": "order by id"})
+	if err != nil || len(rows) != 3 || rows[2]["id"] != int64(4) {
+		t.Errorf("nested: %s %v", err, rows)
+	}
+
+	// db.WithTx with tx ctx is nested too
+	err = db.WithTx(ctx, nil, func(tx *Tx) error {
+		tx.Insert("test", Values{"id": 5, "name": "e"})
+		db.WithTx(tx.Context(), nil, func(inner *Tx) error {
+			if inner != tx {
+				t.Errorf("not nested by tx ctx")
+			}
+			inner.Insert("test", Values{"id": 6, "name": "f"})
+			return fmt.Errorf("fail")
+		})
+		return nil
+	})
+	rows, _ = db.SelectMap("test", Where{"_field": "id", "_other": "order by id"})
+	if err != nil || len(rows) != 4 || rows[3]["id"] != int64(5) {
+		t.Errorf("nested by ctx: %s %v", err, rows)
+	}
+
+	// retry on deadlock
+	TxBackoff = time.Millisecond
+	tries := 0
+	err = db.WithTx(ctx, nil, func(tx *Tx) error {
+		tries++
+		if tries < 3 {
+			return fmt.Errorf("Error 1213: Deadlock found when trying to get lock")
+		}
+		return nil
+	})
+	if err != nil || tries != 3 {
+		t.Errorf("retry: %s %d", err, tries)
+	}
+
+	tries = 0
+	err = db.WithTx(ctx, nil, func(tx *Tx) error {
+		tries++
+		return fmt.Errorf("pq: could not serialize access due to concurrent update")
+	})
+	if err == nil || tries != TxRetry+1 {
+		t.Errorf("retry limit: %s %d", err, tries)
+	}
+}
+
+func TestReplica(t *testing.T) {
+	log.Install("stdout")
+	Install(DBConf{
+		"rw": []string{"sqlite3",
+			"file:primary?mode=memory&cache=shared",
+			"file:replica1?mode=memory&cache=shared",
+			"file:replica2?mode=memory&cache=shared",
+		},
+	})
+	db := GetDB("rw")
+	defer db.Close()
+
+	db.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+	db.Insert("test", Values{"id": 1, "name": "primary"})
+	for i, r := range db.replicas.dbs {
+		r.Exec("create table if not exists test(id integer not null primary key, name text, time datetime)")
+		r.Exec("insert into test(id, name) values(1, ?)", fmt.Sprintf("replica%d", i+1))
+	}
+
+	name := func(tool *DBTool) string {
+		rows, err := tool.SelectMap("test", Where{"id": 1})
+		if err != nil || len(rows) == 0 {
+			t.Fatalf("select: %s", err)
+		}
+		return fmt.Sprintf("%s", rows[0]["name"])
+	}
+
+	// round robin
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		seen[name(db.DBTool)]++
+	}
+	if seen["replica1"] != 2 || seen["replica2"] != 2 {
+		t.Errorf("round robin: %v", seen)
+	}
+
+	if n := name(db.Primary().DBTool); n != "primary" {
+		t.Errorf("force primary: %s", n)
+	}
+
+	db.WithTx(context.Background(), nil, func(tx *Tx) error {
+		if n := name(tx.DBTool); n != "primary" {
+			t.Errorf("tx: %s", n)
+		}
+		return nil
+	})
+
+	// least in use
+	db.SetReplicaPolicy(ReplicaLeastInUse)
+	rows, _ := db.replicas.dbs[0].DB.Query("select * from test")
+	for i := 0; i < 3; i++ {
+		if n := name(db.DBTool); n != "replica2" {
+			t.Errorf("least in use: %s", n)
+		}
+	}
+	rows.Close()
+
+	// skip down replica, primary if all down
+	db.markDown(db.replicas.dbs[1], driver.ErrBadConn)
+	if n := name(db.DBTool); n != "replica1" {
+		t.Errorf("down replica: %s", n)
+	}
+	db.markDown(db.replicas.dbs[0], driver.ErrBadConn)
+	if n := name(db.DBTool); n != "primary" {
+		t.Errorf("all down: %s", n)
+	}
+
+	ctx := context.Background()
+	if !isConnError(ctx, fmt.Errorf("query: %w", driver.ErrBadConn)) || isConnError(ctx, fmt.Errorf("syntax error")) {
+		t.Errorf("conn error")
+	}
+	// timeout and cancel are not replica down
+	if isConnError(ctx, fmt.Errorf("query: %w", context.DeadlineExceeded)) || isConnError(ctx, context.Canceled) {
+		t.Errorf("context error is conn error")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if isConnError(canceled, driver.ErrBadConn) {
+		t.Errorf("conn error after ctx done")
+	}
+
+	// next wrap around uint32
+	db.replicas.down[0], db.replicas.down[1] = 0, 0
+	db.replicas.next = math.MaxUint32 - 1
+	for i := 0; i < 4; i++ {
+		if db.replica() == nil {
+			t.Errorf("no replica after wrap")
+		}
+	}
+}
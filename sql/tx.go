@@ -0,0 +1,156 @@
+// transaction in func, commit on nil and rollback on error or panic
+// err := db.WithTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(tx *Tx) error {
+//     tx.Insert(...)
+//     // nested by savepoint, only rollback to it on error
+//     return tx.WithTx(ctx, func(tx *Tx) error {...})
+// })
+// db.WithTx with tx.Context() in func is also nested, so helpers can call db.WithTx either way
+// db.WithTx with other ctx start a new transaction on another connection
+// retry whole func on deadlock or serialization failure, so func should have no other side effect
+
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/JoveYu/zgo/log"
+	"github.com/JoveYu/zgo/sql/builder"
+)
+
+// retry times and first backoff, double every retry
+var (
+	TxRetry   = 3
+	TxBackoff = 10 * time.Millisecond
+)
+
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	log.Get("sql").Infow("", log.ContextFields(ctx), "ep", d.driver, "name", d.name, "func", "begin")
+	tx, err := d.DB.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	ztx := Tx{
+		Tx: tx,
+		db: d,
+	}
+	ztx.DBTool = &DBTool{tx: &ztx}
+	ztx.ctx = context.WithValue(ctx, txKey{}, &ztx)
+	return &ztx, nil
+}
+
+type txKey struct{}
+
+// ctx of BeginTx carrying this Tx, db.WithTx with it is nested by savepoint
+func (t *Tx) Context() context.Context {
+	return t.ctx
+}
+
+// nested by savepoint if ctx is from Tx.Context of same db, opts is ignored then
+func (d *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	if t, ok := ctx.Value(txKey{}).(*Tx); ok && t.db.DB == d.DB {
+		return t.WithTx(ctx, fn)
+	}
+	for retry := 0; ; retry++ {
+		err := d.withTx(ctx, opts, fn)
+		if err == nil || retry >= TxRetry || !isRetryable(err) {
+			return err
+		}
+
+		backoff := TxBackoff << uint(retry)
+		backoff += time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+		log.Get("sql").Warnw("", log.ContextFields(ctx), "ep", d.driver, "name", d.name, "func", "retry",
+			"retry", retry+1, "backoff", int64(backoff/time.Microsecond), "err", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (d *DB) withTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) (err error) {
+	tx, err := d.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// nested transaction by savepoint, error is returned to outer WithTx
+func (t *Tx) WithTx(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	t.savepoint++
+	defer func() {
+		t.savepoint--
+	}()
+
+	name := fmt.Sprintf("zgo_sp%d", t.savepoint)
+	sqlserver := t.db.builder.Dialect() == builder.SQLServer
+
+	save := "SAVEPOINT " + name
+	if sqlserver {
+		save = "SAVE TRANSACTION " + name
+	}
+	if _, err = t.ExecContext(ctx, save); err != nil {
+		return err
+	}
+
+	rollback := func() {
+		if sqlserver {
+			t.ExecContext(ctx, "ROLLBACK TRANSACTION "+name)
+		} else {
+			t.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		}
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			rollback()
+			panic(r)
+		}
+	}()
+
+	if err = fn(t); err != nil {
+		rollback()
+		return err
+	}
+	// sqlserver has no release
+	if !sqlserver {
+		_, err = t.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	}
+	return err
+}
+
+// mysql 1213 deadlock, pg 40001 serialization failure and 40P01 deadlock
+func isRetryable(err error) bool {
+	var state interface {
+		SQLState() string
+	}
+	if errors.As(err, &state) {
+		code := state.SQLState()
+		return code == "40001" || code == "40P01"
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1213") ||
+		strings.Contains(msg, "SQLSTATE 40001") || strings.Contains(msg, "SQLSTATE 40P01") ||
+		strings.Contains(msg, "could not serialize access") || strings.Contains(msg, "deadlock detected")
+}
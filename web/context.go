@@ -40,6 +40,7 @@ type Context struct {
 
 func NewContext(w http.ResponseWriter, r *http.Request) Context {
 	return Context{
+		Context:        r.Context(),
 		Request:        r,
 		ResponseWriter: w,
 		Charset:        "utf-8",
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"net/http"
+
+	"github.com/JoveYu/zgo/log"
+)
+
+// show and change log level at runtime, mount it yourself
+// server.Router("GET", "^/admin/log$", web.LogLevelHandler)
+// server.Router("POST", "^/admin/log$", web.LogLevelHandler)
+//
+// GET return {"default":"info","sql":"warn"}
+// POST name=sql&level=debug, name is "default" for DefaultLog
+func LogLevelHandler(ctx Context) {
+	if ctx.Method() == http.MethodPost || ctx.Method() == http.MethodPut {
+		name := ctx.GetForm("name")
+		if name == "" {
+			name = "default"
+		}
+		level, err := log.ParseLevel(ctx.GetForm("level"))
+		if err != nil {
+			ctx.Abort(http.StatusBadRequest, err.Error())
+			return
+		}
+		l := log.Lookup(name)
+		if l == nil {
+			ctx.Abort(http.StatusNotFound, "logger not found")
+			return
+		}
+		l.ChangeLevel(level, "http "+ctx.ClientIP())
+	}
+	ctx.WriteJSON(log.Levels())
+}
@@ -49,7 +49,7 @@ func (s *Server) StaticFile(path string, dir string) {
 func (s *Server) Router(method string, path string, handlers ...ContextHandlerFunc) {
 	cr, err := regexp.Compile(path)
 	if err != nil {
-		log.Warn("can not add route [%s] %s", path, err)
+		log.Get("web").Warn("can not add route [%s] %s", path, err)
 		return
 	}
 
@@ -64,6 +64,13 @@ func (s *Server) Router(method string, path string, handlers ...ContextHandlerFu
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	tstart := time.Now()
 
+	// request id from upstream or new one
+	rid := r.Header.Get(log.RequestIDHeader)
+	if rid == "" {
+		rid = log.NewRequestID()
+	}
+	r = r.WithContext(log.WithRequestID(r.Context(), rid))
+
 	ctx := NewContext(w, r)
 	defer s.LogRequest(tstart, &ctx)
 
@@ -75,26 +82,26 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// debug req
 		data, err := httputil.DumpRequest(r, true)
 		if err != nil {
-			log.Error("can not dump req: %s", err)
+			log.Get("web").Error("can not dump req: %s", err)
 		}
 		for _, b := range strings.Split(string(data), "\n") {
-			log.Debug("> %s", b)
+			log.Get("web").Debug("> %s", b)
 		}
 
 		// debug resp
 		defer func(ctx Context) {
-			log.Debug("< %s %d %s", ctx.Request.Proto,
+			log.Get("web").Debug("< %s %d %s", ctx.Request.Proto,
 				ctx.Flag.Status, http.StatusText(ctx.Flag.Status),
 			)
 			for k, v := range ctx.ResponseWriter.Header() {
 				for _, vv := range v {
-					log.Debug("< %s: %s", k, vv)
+					log.Get("web").Debug("< %s: %s", k, vv)
 				}
 				// XXX Content-Length and Date is missing
 			}
-			log.Debug("<")
+			log.Get("web").Debug("<")
 			for _, b := range strings.Split(ctx.DebugBody.String(), "\n") {
-				log.Debug("< %s", b)
+				log.Get("web").Debug("< %s", b)
 			}
 		}(ctx)
 	}
@@ -103,6 +110,7 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// default header
 	ctx.SetHeader("X-Powered-By", PoweredBy)
+	ctx.SetHeader(log.RequestIDHeader, rid)
 	ctx.SetContentType("text/plain")
 
 	for _, router := range s.Routers {
@@ -145,7 +153,7 @@ func (s *Server) Run(addr string) error {
 
 func (s *Server) LogRequest(tstart time.Time, ctx *Context) {
 
-	log.Info("%d|%s|%s|%s|%s|%d",
+	log.Get("web").InfoCtx(ctx, "%d|%s|%s|%s|%s|%d",
 		ctx.Flag.Status, ctx.Method(), ctx.URL().Path,
 		ctx.Query().Encode(), ctx.ClientIP(),
 		time.Since(tstart)/time.Microsecond,